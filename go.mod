@@ -9,6 +9,7 @@ require (
 	github.com/hashicorp/waypoint v0.2.0
 	github.com/hashicorp/waypoint-plugin-sdk v0.0.0-20201202203308-140d0145b90e
 	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
 
 replace golang.org/x/sys => golang.org/x/sys v0.0.0-20200826173525-f9321e4c35a6
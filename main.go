@@ -12,6 +12,6 @@ func main() {
 		// &builder.Builder{},
 		// &registry.Registry{},
 		&platform.Platform{},
-		// &release.ReleaseManager{},
+		&platform.ReleaseManager{},
 	))
 }
@@ -2,12 +2,123 @@ package platform
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/digitalocean/godo"
 	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"gopkg.in/yaml.v3"
 )
 
+const (
+	// tokenURL is where access tokens are generated.
+	tokenURL = "https://cloud.digitalocean.com/account/api/tokens/new"
+
+	// accountStatusActive is the status of an account in good standing.
+	accountStatusActive = "active"
+
+	// doctlDefaultContext is the auth context whose token doctl stores as
+	// access-token.
+	doctlDefaultContext = "default"
+)
+
+// storedTokenSource describes the token saved by waypoint auth.
+const storedTokenSource = "the token saved by waypoint auth"
+
+// resolveToken returns the access token to use and where it came from. The
+// first of these that is set is used:
+//
+//  1. access_token
+//  2. the doctl auth context named by context
+//  3. DIGITALOCEAN_ACCESS_TOKEN
+//  4. DIGITALOCEAN_TOKEN
+//  5. the doctl auth context named by DIGITALOCEAN_CONTEXT
+//
+// If none are, the token is empty and the token saved by waypoint auth is
+// used when there is one. doctl's config is only read when a context is
+// named, so doctl's current context is never picked up unasked.
+func resolveToken(configured, authContext string) (string, string, error) {
+	if configured != "" {
+		return configured, "access_token", nil
+	}
+
+	if authContext != "" {
+		token, err := doctlToken(doctlConfigPath(), authContext)
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading doctl auth context %q: %s", authContext, err)
+		}
+		return token, fmt.Sprintf("doctl auth context %q", authContext), nil
+	}
+
+	for _, env := range []string{"DIGITALOCEAN_ACCESS_TOKEN", "DIGITALOCEAN_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			return token, env, nil
+		}
+	}
+
+	if name := os.Getenv("DIGITALOCEAN_CONTEXT"); name != "" {
+		token, err := doctlToken(doctlConfigPath(), name)
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading doctl auth context %q set by DIGITALOCEAN_CONTEXT: %s", name, err)
+		}
+		return token, fmt.Sprintf("doctl auth context %q (DIGITALOCEAN_CONTEXT)", name), nil
+	}
+
+	return "", "", nil
+}
+
+// doctlConfig is the part of doctl's config file that holds its auth
+// contexts. The token of the default context is stored as access-token.
+type doctlConfig struct {
+	AccessToken  string            `yaml:"access-token"`
+	AuthContexts map[string]string `yaml:"auth-contexts"`
+}
+
+// doctlConfigPath returns the path of doctl's config file, which is in
+// $XDG_CONFIG_HOME/doctl or the user's config directory. It is empty if
+// neither is known.
+func doctlConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir, _ = os.UserConfigDir()
+	}
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, "doctl", "config.yaml")
+}
+
+// doctlToken returns the token of the named auth context in the doctl config
+// file at path.
+func doctlToken(path, name string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var cfg doctlConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("%s: %s", path, err)
+	}
+
+	token := cfg.AuthContexts[name]
+	if name == doctlDefaultContext && cfg.AccessToken != "" {
+		token = cfg.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("%s: auth context not found", path)
+	}
+
+	return token, nil
+}
+
 func (p *Platform) ValidateAuthFunc() interface{} {
 	return p.validateAuth
 }
@@ -39,21 +150,30 @@ func (p *Platform) AuthFunc() interface{} {
 func (p *Platform) validateAuth(
 	ctx context.Context,
 	ui terminal.UI,
+	dir *datadir.Project,
 ) error {
 	s := ui.Status()
 	defer s.Close()
 	s.Update("Validating authentication")
 
-	if p.config.AccessToken != "" {
-		s.Update("Using configured access token")
-		account, _, err := p.client.Account.Get(ctx)
-		if err == nil {
-			s.Update(fmt.Sprintf("Authenticated as %s (%s)", account.Email, account.UUID))
-			return nil
-		}
+	p.useStoredToken(dir)
+
+	if p.config.AccessToken == "" {
+		s.Step(terminal.StatusError, "No access token found in access_token, context, DIGITALOCEAN_ACCESS_TOKEN, "+
+			"DIGITALOCEAN_TOKEN, DIGITALOCEAN_CONTEXT or waypoint auth")
+		return fmt.Errorf("Unable to Authenticate")
+	}
+
+	s.Update(fmt.Sprintf("Using access token from %s", p.tokenSource))
+	account, err := verifyAccount(ctx, p.client)
+	if err != nil {
+		s.Step(terminal.StatusError, fmt.Sprintf("Access token from %s: %s", p.tokenSource, err))
+		return fmt.Errorf("Unable to Authenticate")
 	}
 
-	return fmt.Errorf("Unable to Authenticate")
+	s.Step(terminal.StatusOK, fmt.Sprintf("Authenticated as %s (%s) using access token from %s",
+		account.Email, account.UUID, p.tokenSource))
+	return nil
 }
 
 // A AuthFunc does not have a strict signature, you can define the parameters
@@ -77,11 +197,80 @@ func (p *Platform) validateAuth(
 func (p *Platform) authenticate(
 	ctx context.Context,
 	ui terminal.UI,
+	dir *datadir.Project,
 ) (*component.AuthResult, error) {
+	if !ui.Interactive() {
+		ui.Output("Set access_token, context or DIGITALOCEAN_ACCESS_TOKEN to use an access token generated from: %s", tokenURL)
+		return &component.AuthResult{Authenticated: false}, nil
+	}
+
+	ui.Output("Generate an access token with read and write scopes from: %s", tokenURL)
+
+	token, err := ui.Input(&terminal.Input{
+		Prompt: "DigitalOcean access token: ",
+		Secret: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading access token: %s", err)
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return &component.AuthResult{Authenticated: false}, nil
+	}
+
+	client := godo.NewFromToken(token)
+	account, err := verifyAccount(ctx, client)
+	if err != nil {
+		return nil, err
+	}
 
-	ui.Output("Configure an access token generate from: https://cloud.digitalocean.com/account/api/tokens/new")
+	if err := writeToken(dir, token); err != nil {
+		return nil, fmt.Errorf("Error saving access token: %s", err)
+	}
+
+	p.config.AccessToken = token
+	p.tokenSource = storedTokenSource
+	p.client = client
+
+	ui.Output("Authenticated as %s (%s)", account.Email, account.UUID, terminal.WithSuccessStyle())
+
+	return &component.AuthResult{Authenticated: true}, nil
+}
+
+// verifyAccount returns the account the client's token belongs to, and an
+// error if the token isn't valid or the account isn't active.
+func verifyAccount(ctx context.Context, client *godo.Client) (*godo.Account, error) {
+	account, _, err := client.Account.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error verifying access token: %s", err)
+	}
+
+	if account.Status != accountStatusActive {
+		msg := fmt.Sprintf("account %s (%s) is %s, not active", account.Email, account.UUID, account.Status)
+		if account.StatusMessage != "" {
+			msg += ": " + account.StatusMessage
+		}
+		return nil, errors.New(msg)
+	}
+
+	return account, nil
+}
 
-	// TODO: Prompt to provide token
+// useStoredToken switches to the access token saved by waypoint auth if no
+// token is configured, and reports whether it did.
+func (p *Platform) useStoredToken(dir *datadir.Project) bool {
+	if p.config.AccessToken != "" {
+		return false
+	}
+
+	token := readToken(dir)
+	if token == "" {
+		return false
+	}
 
-	return &component.AuthResult{Authenticated: false}, nil
+	p.config.AccessToken = token
+	p.tokenSource = storedTokenSource
+	p.client = godo.NewFromToken(token)
+	return true
 }
@@ -0,0 +1,204 @@
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDoctlConfig = `access-token: default-token
+context: staging
+auth-contexts:
+  staging: staging-token
+  production: production-token
+`
+
+// setenv sets the environment variables in env for the rest of the test,
+// unsetting those that are empty.
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		if v == "" {
+			os.Unsetenv(k)
+		} else {
+			os.Setenv(k, v)
+		}
+
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestDoctlToken(t *testing.T) {
+	path, err := ioutil.TempDir("", "doctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	config := filepath.Join(path, "config.yaml")
+	if err := ioutil.WriteFile(config, []byte(testDoctlConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		context   string
+		wantToken string
+	}{
+		{"named context", "production", "production-token"},
+		{"default context", "default", "default-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := doctlToken(config, tt.context)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if token != tt.wantToken {
+				t.Errorf("got %q, want %q", token, tt.wantToken)
+			}
+		})
+	}
+
+	t.Run("unknown context", func(t *testing.T) {
+		_, err := doctlToken(config, "missing")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("no config file", func(t *testing.T) {
+		_, err := doctlToken(filepath.Join(path, "missing.yaml"), "default")
+		if !os.IsNotExist(err) {
+			t.Errorf("got %v, want a not exist error", err)
+		}
+	})
+}
+
+func TestResolveToken(t *testing.T) {
+	path, err := ioutil.TempDir("", "xdg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	if err := os.MkdirAll(filepath.Join(path, "doctl"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, "doctl", "config.yaml"), []byte(testDoctlConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		configured string
+		context    string
+		env        map[string]string
+		wantToken  string
+		wantSource string
+	}{
+		{
+			name:       "access_token first",
+			configured: "configured-token",
+			context:    "production",
+			env:        map[string]string{"DIGITALOCEAN_ACCESS_TOKEN": "env-token"},
+			wantToken:  "configured-token",
+			wantSource: "access_token",
+		},
+		{
+			name:       "context before the environment",
+			context:    "production",
+			env:        map[string]string{"DIGITALOCEAN_ACCESS_TOKEN": "env-token"},
+			wantToken:  "production-token",
+			wantSource: `doctl auth context "production"`,
+		},
+		{
+			name:       "DIGITALOCEAN_ACCESS_TOKEN",
+			env:        map[string]string{"DIGITALOCEAN_ACCESS_TOKEN": "env-token", "DIGITALOCEAN_TOKEN": "other-token"},
+			wantToken:  "env-token",
+			wantSource: "DIGITALOCEAN_ACCESS_TOKEN",
+		},
+		{
+			name:       "DIGITALOCEAN_TOKEN",
+			env:        map[string]string{"DIGITALOCEAN_TOKEN": "other-token", "DIGITALOCEAN_CONTEXT": "production"},
+			wantToken:  "other-token",
+			wantSource: "DIGITALOCEAN_TOKEN",
+		},
+		{
+			name:       "DIGITALOCEAN_CONTEXT",
+			env:        map[string]string{"DIGITALOCEAN_CONTEXT": "production"},
+			wantToken:  "production-token",
+			wantSource: `doctl auth context "production" (DIGITALOCEAN_CONTEXT)`,
+		},
+		{
+			name: "doctl's current context is not used",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{
+				"XDG_CONFIG_HOME":           path,
+				"DIGITALOCEAN_ACCESS_TOKEN": "",
+				"DIGITALOCEAN_TOKEN":        "",
+				"DIGITALOCEAN_CONTEXT":      "",
+			}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			setenv(t, env)
+
+			token, source, err := resolveToken(tt.configured, tt.context)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if token != tt.wantToken || source != tt.wantSource {
+				t.Errorf("got %q from %q, want %q from %q", token, source, tt.wantToken, tt.wantSource)
+			}
+		})
+	}
+
+	t.Run("unknown context", func(t *testing.T) {
+		setenv(t, map[string]string{"XDG_CONFIG_HOME": path})
+		if _, _, err := resolveToken("", "missing"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("broken doctl config", func(t *testing.T) {
+		broken, err := ioutil.TempDir("", "xdg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(broken)
+
+		if err := os.MkdirAll(filepath.Join(broken, "doctl"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(broken, "doctl", "config.yaml"), []byte("{"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		setenv(t, map[string]string{
+			"XDG_CONFIG_HOME":           broken,
+			"DIGITALOCEAN_ACCESS_TOKEN": "",
+			"DIGITALOCEAN_TOKEN":        "",
+			"DIGITALOCEAN_CONTEXT":      "",
+		})
+		if token, _, err := resolveToken("", ""); err != nil || token != "" {
+			t.Errorf("got %q, %v without a context, want doctl's config left unread", token, err)
+		}
+		if _, _, err := resolveToken("", "production"); err == nil {
+			t.Error("expected an error reading the named context")
+		}
+	})
+}
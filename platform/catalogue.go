@@ -0,0 +1,186 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// catalogue holds the regions, tiers and instance sizes App Platform
+// currently offers.
+type catalogue struct {
+	regions map[string]*godo.AppRegion
+	tiers   map[string]*godo.AppTier
+	sizes   map[string]*godo.AppInstanceSize
+}
+
+func (p *Platform) fetchCatalogue(ctx context.Context) (*catalogue, error) {
+	regions, _, err := p.client.Apps.ListRegions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing App Platform regions: %s", err)
+	}
+
+	tiers, _, err := p.client.Apps.ListTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing App Platform tiers: %s", err)
+	}
+
+	sizes, _, err := p.client.Apps.ListInstanceSizes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing App Platform instance sizes: %s", err)
+	}
+
+	c := &catalogue{
+		regions: map[string]*godo.AppRegion{},
+		tiers:   map[string]*godo.AppTier{},
+		sizes:   map[string]*godo.AppInstanceSize{},
+	}
+	for _, r := range regions {
+		c.regions[r.Slug] = r
+	}
+	for _, t := range tiers {
+		c.tiers[t.Slug] = t
+	}
+	for _, s := range sizes {
+		c.sizes[s.Slug] = s
+	}
+
+	return c, nil
+}
+
+// prices returns the monthly price in USD of each instance size in the
+// catalogue. Sizes whose price can't be parsed are left out.
+func (c *catalogue) prices() map[string]float64 {
+	prices := map[string]float64{}
+	for slug, s := range c.sizes {
+		if price, err := strconv.ParseFloat(s.USDPerMonth, 64); err == nil {
+			prices[slug] = price
+		}
+	}
+
+	return prices
+}
+
+// validate checks the region and the instance sizes and counts of every
+// component in spec against the catalogue.
+func (c *catalogue) validate(spec *godo.AppSpec) error {
+	if spec.Region != "" {
+		r, ok := c.regions[spec.Region]
+		if !ok {
+			return fmt.Errorf("unknown region %q%s", spec.Region, suggest(spec.Region, c.regionSlugs()))
+		}
+		if r.Disabled {
+			return fmt.Errorf("region %q is not currently available: %s", spec.Region, r.Reason)
+		}
+	}
+
+	check := func(kind, name, size string, count int64) error {
+		if count < 0 {
+			return fmt.Errorf("%s %q: instance_count must not be negative", kind, name)
+		}
+		if size == "" {
+			return nil
+		}
+
+		s, ok := c.sizes[size]
+		if !ok {
+			return fmt.Errorf("%s %q: unknown instance size %q%s", kind, name, size, suggest(size, c.sizeSlugs()))
+		}
+		if _, ok := c.tiers[s.TierSlug]; s.TierSlug != "" && !ok {
+			return fmt.Errorf("%s %q: instance size %q belongs to tier %q, which is not currently available",
+				kind, name, size, s.TierSlug)
+		}
+
+		return nil
+	}
+
+	for _, s := range spec.Services {
+		if err := check("service", s.Name, s.InstanceSizeSlug, s.InstanceCount); err != nil {
+			return err
+		}
+	}
+	for _, w := range spec.Workers {
+		if err := check("worker", w.Name, w.InstanceSizeSlug, w.InstanceCount); err != nil {
+			return err
+		}
+	}
+	for _, j := range spec.Jobs {
+		if err := check("job", j.Name, j.InstanceSizeSlug, j.InstanceCount); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *catalogue) regionSlugs() []string {
+	var slugs []string
+	for slug, r := range c.regions {
+		if !r.Disabled {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+func (c *catalogue) sizeSlugs() []string {
+	var slugs []string
+	for slug := range c.sizes {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// suggest returns a hint naming the closest valid slug to s, or listing the
+// valid slugs if none are close enough to be a likely typo.
+func suggest(s string, slugs []string) string {
+	best, bestDist := "", -1
+	for _, slug := range slugs {
+		d := levenshtein(strings.ToLower(s), slug)
+		if bestDist == -1 || d < bestDist {
+			best, bestDist = slug, d
+		}
+	}
+
+	if best == "" || bestDist > len(s)/2+1 {
+		return fmt.Sprintf(", must be one of: %s", strings.Join(slugs, ", "))
+	}
+
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
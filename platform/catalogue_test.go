@@ -0,0 +1,104 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func testCatalogue() *catalogue {
+	return &catalogue{
+		regions: map[string]*godo.AppRegion{
+			"ams": {Slug: "ams"},
+			"nyc": {Slug: "nyc"},
+			"sfo": {Slug: "sfo", Disabled: true, Reason: "at capacity"},
+		},
+		tiers: map[string]*godo.AppTier{
+			"basic":        {Slug: "basic"},
+			"professional": {Slug: "professional"},
+		},
+		sizes: map[string]*godo.AppInstanceSize{
+			"basic-xxs":       {Slug: "basic-xxs", TierSlug: "basic"},
+			"basic-xs":        {Slug: "basic-xs", TierSlug: "basic"},
+			"professional-xs": {Slug: "professional-xs", TierSlug: "professional"},
+			"legacy-xs":       {Slug: "legacy-xs", TierSlug: "legacy"},
+		},
+	}
+}
+
+func TestCatalogueValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		spec *godo.AppSpec
+		err  string
+	}{
+		{
+			"valid",
+			&godo.AppSpec{
+				Region:   "ams",
+				Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "basic-xs", InstanceCount: 2}},
+				Workers:  []*godo.AppWorkerSpec{{Name: "queue", InstanceSizeSlug: "professional-xs"}},
+			},
+			"",
+		},
+		{"default region and size", &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web"}}}, ""},
+		{"region typo", &godo.AppSpec{Region: "nyc1"}, `did you mean "nyc"?`},
+		{"disabled region", &godo.AppSpec{Region: "sfo"}, "at capacity"},
+		{
+			"size typo",
+			&godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "basic-xxxs"}}},
+			`did you mean "basic-xxs"?`,
+		},
+		{
+			"unknown size",
+			&godo.AppSpec{Jobs: []*godo.AppJobSpec{{Name: "migrate", InstanceSizeSlug: "enormous"}}},
+			"must be one of: basic-xs, basic-xxs, legacy-xs, professional-xs",
+		},
+		{
+			"unavailable tier",
+			&godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "legacy-xs"}}},
+			`tier "legacy"`,
+		},
+		{
+			"negative count",
+			&godo.AppSpec{Workers: []*godo.AppWorkerSpec{{Name: "queue", InstanceCount: -1}}},
+			"must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testCatalogue().validate(tt.spec)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"nyc", "nyc", 0},
+		{"nyc1", "nyc", 1},
+		{"basic-xxxs", "basic-xxs", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// defaultInstanceSize is the size App Platform uses for components that
+// don't set one.
+const defaultInstanceSize = "basic-xxs"
+
+// checkCost estimates the monthly cost of spec from the prices in the
+// catalogue, reports it along with the change from the app's active
+// deployment, and fails if it exceeds the configured max_monthly_cost. It
+// also warns if the account balance is negative.
+func (p *Platform) checkCost(
+	ctx context.Context,
+	log hclog.Logger,
+	u terminal.Status,
+	catalogue *catalogue,
+	spec *godo.AppSpec,
+	existing *godo.App,
+) error {
+	u.Update("Estimating monthly cost")
+
+	var current *godo.AppSpec
+	if existing != nil {
+		current = existing.Spec
+		if existing.ActiveDeployment != nil && existing.ActiveDeployment.Spec != nil {
+			current = existing.ActiveDeployment.Spec
+		}
+	}
+
+	status, msg, err := estimateCost(spec, current, catalogue.prices(), p.config.MaxMonthlyCost)
+	u.Step(status, msg)
+	if err != nil {
+		return err
+	}
+
+	// Reading the balance requires billing access, which not every token
+	// has, so failing to read it doesn't stop the deployment.
+	balance, _, err := p.client.Balance.Get(ctx)
+	if err != nil {
+		log.Debug("unable to read account balance", "error", err)
+		return nil
+	}
+
+	if b, err := strconv.ParseFloat(balance.AccountBalance, 64); err == nil && b < 0 {
+		u.Step(terminal.StatusWarn, fmt.Sprintf("Account balance is negative: $%.2f", b))
+	}
+
+	return nil
+}
+
+// estimateCost returns the status and message reporting the estimated
+// monthly cost of spec, and an error if it exceeds max. Without a price for
+// every size spec uses there is no estimate, which is only an error if max
+// is set.
+func estimateCost(spec, current *godo.AppSpec, prices map[string]float64, max float64) (string, string, error) {
+	if missing := unpriced(spec, prices); len(missing) > 0 {
+		msg := fmt.Sprintf("Monthly cost estimate unavailable, no price for instance sizes: %s", strings.Join(missing, ", "))
+		if max > 0 {
+			return terminal.StatusError, msg, fmt.Errorf("unable to check max_monthly_cost, no price for instance sizes: %s",
+				strings.Join(missing, ", "))
+		}
+		return terminal.StatusWarn, msg, nil
+	}
+
+	cost := monthlyCost(spec, prices)
+	msg := costMessage(cost, current, prices)
+	if max > 0 && cost > max {
+		return terminal.StatusError, msg, fmt.Errorf("estimated monthly cost of $%.2f exceeds max_monthly_cost of $%.2f",
+			cost, max)
+	}
+
+	return terminal.StatusOK, msg, nil
+}
+
+// costMessage describes the estimated monthly cost and its change from the
+// active deployment's spec, current. The change is unknown if current uses
+// instance sizes that are no longer offered.
+func costMessage(cost float64, current *godo.AppSpec, prices map[string]float64) string {
+	msg := fmt.Sprintf("Estimated monthly cost: $%.2f", cost)
+	if current == nil {
+		return msg
+	}
+
+	if missing := unpriced(current, prices); len(missing) > 0 {
+		return fmt.Sprintf("%s (change from the active deployment unknown, it uses instance sizes no longer offered: %s)",
+			msg, strings.Join(missing, ", "))
+	}
+
+	return fmt.Sprintf("%s (%+.2f from the active deployment)", msg, cost-monthlyCost(current, prices))
+}
+
+// unpriced returns the instance sizes used by spec that have no price, in
+// sorted order.
+func unpriced(spec *godo.AppSpec, prices map[string]float64) []string {
+	var missing []string
+	for size := range sizeCounts(spec) {
+		if _, ok := prices[size]; !ok {
+			missing = append(missing, size)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// sizeCounts returns the total number of instances of each size used by the
+// services and workers in spec. Jobs are only billed for the time they run
+// and databases aren't priced by instance size, so neither is included.
+func sizeCounts(spec *godo.AppSpec) map[string]int64 {
+	counts := map[string]int64{}
+	if spec == nil {
+		return counts
+	}
+
+	add := func(size string, count int64) {
+		if size == "" {
+			size = defaultInstanceSize
+		}
+		if count == 0 {
+			count = 1
+		}
+		counts[size] += count
+	}
+
+	for _, s := range spec.Services {
+		add(s.InstanceSizeSlug, s.InstanceCount)
+	}
+	for _, w := range spec.Workers {
+		add(w.InstanceSizeSlug, w.InstanceCount)
+	}
+
+	return counts
+}
+
+// monthlyCost returns the monthly cost in USD of the services and workers in
+// spec given the monthly price of each instance size.
+func monthlyCost(spec *godo.AppSpec, prices map[string]float64) float64 {
+	var cost float64
+	for size, count := range sizeCounts(spec) {
+		cost += prices[size] * float64(count)
+	}
+
+	return cost
+}
@@ -0,0 +1,109 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+func TestMonthlyCost(t *testing.T) {
+	prices := map[string]float64{
+		"basic-xxs":       5,
+		"basic-xs":        10,
+		"professional-xs": 12,
+	}
+
+	spec := &godo.AppSpec{
+		Services: []*godo.AppServiceSpec{
+			{Name: "web", InstanceSizeSlug: "professional-xs", InstanceCount: 3},
+			{Name: "api"},
+		},
+		Workers: []*godo.AppWorkerSpec{{Name: "queue", InstanceSizeSlug: "basic-xs", InstanceCount: 2}},
+		Jobs:    []*godo.AppJobSpec{{Name: "migrate", InstanceSizeSlug: "professional-xs"}},
+	}
+
+	counts := sizeCounts(spec)
+	if len(counts) != 3 || counts["professional-xs"] != 3 || counts["basic-xxs"] != 1 || counts["basic-xs"] != 2 {
+		t.Errorf("unexpected size counts: %v", counts)
+	}
+
+	if got, want := monthlyCost(spec, prices), 61.0; got != want {
+		t.Errorf("got $%.2f, want $%.2f", got, want)
+	}
+
+	if got := monthlyCost(nil, prices); got != 0 {
+		t.Errorf("got $%.2f for a nil spec, want $0.00", got)
+	}
+}
+
+func TestCostMessage(t *testing.T) {
+	prices := map[string]float64{"basic-xxs": 5, "basic-xs": 10}
+
+	current := &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "basic-xs"}}}
+	retired := &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "retired-xs"}}}
+
+	tests := []struct {
+		name    string
+		current *godo.AppSpec
+		want    string
+	}{
+		{"new app", nil, "Estimated monthly cost: $5.00"},
+		{"change", current, "Estimated monthly cost: $5.00 (-5.00 from the active deployment)"},
+		{"retired size", retired, "Estimated monthly cost: $5.00 (change from the active deployment unknown, " +
+			"it uses instance sizes no longer offered: retired-xs)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := costMessage(5, tt.current, prices); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCataloguePrices(t *testing.T) {
+	c := &catalogue{sizes: map[string]*godo.AppInstanceSize{
+		"basic-xxs": {Slug: "basic-xxs", USDPerMonth: "5.00"},
+		"broken":    {Slug: "broken", USDPerMonth: "n/a"},
+	}}
+
+	prices := c.prices()
+	if len(prices) != 1 || prices["basic-xxs"] != 5 {
+		t.Errorf("unexpected prices: %v", prices)
+	}
+
+	spec := &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "broken"}, {Name: "api"}}}
+	if got := unpriced(spec, prices); len(got) != 1 || got[0] != "broken" {
+		t.Errorf("got unpriced sizes %v, want [broken]", got)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	prices := map[string]float64{"basic-xs": 10}
+	priced := &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "basic-xs", InstanceCount: 2}}}
+	unpriced := &godo.AppSpec{Services: []*godo.AppServiceSpec{{Name: "web"}}}
+
+	tests := []struct {
+		name       string
+		spec       *godo.AppSpec
+		max        float64
+		wantStatus string
+		wantErr    bool
+	}{
+		{"within max", priced, 25, terminal.StatusOK, false},
+		{"over max", priced, 15, terminal.StatusError, true},
+		{"no price without max", unpriced, 0, terminal.StatusWarn, false},
+		{"no price with max", unpriced, 25, terminal.StatusError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, err := estimateCost(tt.spec, nil, prices, tt.max)
+			if status != tt.wantStatus || (err != nil) != tt.wantErr {
+				t.Errorf("got %s, %v, want %s and error %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+		})
+	}
+}
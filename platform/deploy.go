@@ -2,18 +2,30 @@ package platform
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/digitalocean/godo"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
 	"github.com/hashicorp/waypoint/builtin/docker"
 )
 
+const (
+	// submittedPollInterval and submittedTimeout pace the wait for App
+	// Platform to create the deployment submitted when not waiting for it
+	// to finish.
+	submittedPollInterval = 2 * time.Second
+	submittedTimeout      = 30 * time.Second
+)
+
 // DeployConfig holds configuration for a deployment
 type DeployConfig struct {
 	Name             string `hcl:"name,optional"`
@@ -21,9 +33,78 @@ type DeployConfig struct {
 	InstanceSizeSlug string `hcl:"instance_size_slug,optional"`
 	InstanceCount    int64  `hcl:"instance_count,optional"`
 
-	HTTPPort int64  `hcl:"http_port,optional"`
-	Path     string `hcl:"path,optional"`
-
+	// NameTemplate names the app of each workspace. {{app}} is replaced
+	// with the name and {{workspace}} with the workspace.
+	NameTemplate string `hcl:"name_template,optional"`
+
+	// AppID is the ID of the app to deploy to. Without it, the app the
+	// previous deployment went to is used, or one is found by name.
+	AppID string `hcl:"app_id,optional"`
+
+	HTTPPort      int64    `hcl:"http_port,optional"`
+	Path          string   `hcl:"path,optional"`
+	Routes        []string `hcl:"routes,optional"`
+	InternalPorts []int64  `hcl:"internal_ports,optional"`
+
+	HealthCheck *HealthCheckConfig `hcl:"health_check,block"`
+	CORS        *CORSConfig        `hcl:"cors,block"`
+
+	Env       []*EnvConfig `hcl:"env,block"`
+	SecretEnv []*EnvConfig `hcl:"secret_env,block"`
+
+	// DisableEntrypoint stops the Waypoint entrypoint configuration from
+	// being added to the service's environment.
+	DisableEntrypoint bool `hcl:"disable_entrypoint,optional"`
+
+	Services []*ServiceConfig `hcl:"service,block"`
+	Workers  []*WorkerConfig  `hcl:"worker,block"`
+	Jobs     []*JobConfig     `hcl:"job,block"`
+
+	Databases []*DatabaseConfig `hcl:"database,block"`
+
+	// SpecFile is an App Platform app spec, relative to the app's path, to
+	// deploy instead of one built from the configuration. The image is
+	// deployed to the components named by SpecComponents, or by default to
+	// every service, worker and job that doesn't build from a Git
+	// repository. SpecVars are available to it as ${var.<name>}.
+	SpecFile       string            `hcl:"spec_file,optional"`
+	SpecComponents []string          `hcl:"spec_components,optional"`
+	SpecVars       map[string]string `hcl:"spec_vars,optional"`
+
+	// SpecOutput is a path, relative to the app's path, that the app spec
+	// submitted by the deployment is written to. It is written as JSON if
+	// the path ends in .json and as YAML otherwise.
+	SpecOutput string `hcl:"spec_output,optional"`
+
+	// MaxMonthlyCost fails the deployment if the estimated monthly cost of
+	// the app in USD would exceed it.
+	MaxMonthlyCost float64 `hcl:"max_monthly_cost,optional"`
+
+	// Merge keeps the components and variables in the existing app that
+	// aren't managed by Waypoint instead of removing them.
+	Merge bool `hcl:"merge,optional"`
+
+	// Plan shows the changes a deployment would make to the app without
+	// making them. It can also be enabled with WAYPOINT_DIGITALOCEAN_PLAN.
+	Plan bool `hcl:"plan,optional"`
+
+	// Wait set to false returns as soon as the deployment has been
+	// submitted instead of waiting for it to finish.
+	Wait *bool `hcl:"wait,optional"`
+
+	// WaitTimeout and PollInterval are durations, such as "30m" and "10s",
+	// setting how long to wait for a deployment to finish and how often to
+	// check on it.
+	WaitTimeout  string `hcl:"wait_timeout,optional"`
+	PollInterval string `hcl:"poll_interval,optional"`
+
+	// AutoRollback re-submits the spec of the previously active deployment
+	// if the deployment fails.
+	AutoRollback bool `hcl:"auto_rollback,optional"`
+
+	// AuthContext is the name of a doctl auth context to take the access
+	// token from when access_token isn't set.
+	AuthContext string `hcl:"context,optional"`
 	AccessToken string `hcl:"access_token,optional"`
 }
 
@@ -31,6 +112,12 @@ type DeployConfig struct {
 type Platform struct {
 	config DeployConfig
 	client *godo.Client
+
+	// tokenSource describes where the access token came from.
+	tokenSource string
+
+	waitTimeout  time.Duration
+	pollInterval time.Duration
 }
 
 // Config implements Configurable
@@ -46,13 +133,20 @@ func (p *Platform) ConfigSet(config interface{}) error {
 		return fmt.Errorf("Expected *DeployConfig as parameter")
 	}
 
-	tokenFromEnv := os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
-	if c.AccessToken == "" && tokenFromEnv != "" {
-		c.AccessToken = tokenFromEnv
+	token, source, err := resolveToken(c.AccessToken, c.AuthContext)
+	if err != nil {
+		return err
 	}
-
+	c.AccessToken = token
+	p.tokenSource = source
 	p.client = godo.NewFromToken(c.AccessToken)
 
+	c.Region = strings.ToLower(c.Region)
+
+	if err := validateNameTemplate(c.NameTemplate); err != nil {
+		return err
+	}
+
 	if c.Path == "" {
 		c.Path = "/"
 	}
@@ -61,6 +155,53 @@ func (p *Platform) ConfigSet(config interface{}) error {
 		c.HTTPPort = 8080
 	}
 
+	p.waitTimeout, err = parseDuration("wait_timeout", c.WaitTimeout, defaultWaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	p.pollInterval, err = parseDuration("poll_interval", c.PollInterval, defaultPollInterval)
+	if err != nil {
+		return err
+	}
+
+	if c.AutoRollback && c.Wait != nil && !*c.Wait {
+		return fmt.Errorf("auto_rollback requires waiting for the deployment, so it can't be used with wait = false")
+	}
+
+	if c.MaxMonthlyCost < 0 {
+		return fmt.Errorf("max_monthly_cost must not be negative")
+	}
+
+	if err := validateEnvs(c.Env, c.SecretEnv); err != nil {
+		return err
+	}
+
+	if len(c.Services) == 0 {
+		err := validateService(&ServiceConfig{
+			HTTPPort:      c.HTTPPort,
+			Routes:        c.Routes,
+			InternalPorts: c.InternalPorts,
+			HealthCheck:   c.HealthCheck,
+			CORS:          c.CORS,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := validateComponents(c); err != nil {
+		return err
+	}
+
+	if c.SpecFile != "" && len(c.Services)+len(c.Workers)+len(c.Jobs)+len(c.Databases) > 0 {
+		return fmt.Errorf("spec_file can't be combined with service, worker, job or database blocks; " +
+			"add the components to the spec file instead")
+	}
+	if c.SpecFile == "" && (len(c.SpecComponents) > 0 || len(c.SpecVars) > 0) {
+		return fmt.Errorf("spec_components and spec_vars require spec_file to be set")
+	}
+
 	return nil
 }
 
@@ -101,51 +242,108 @@ func (p *Platform) deploy(
 	ui terminal.UI,
 	log hclog.Logger,
 	src *component.Source,
-	img *docker.Image) (*Deployment, error) {
+	job *component.JobInfo,
+	dir *datadir.App,
+	project *datadir.Project,
+	img *docker.Image,
+	deployConfig *component.DeploymentConfig) (*Deployment, error) {
+	p.useStoredToken(project)
+
 	u := ui.Status()
 	defer u.Close()
 	u.Update("Deploying application")
 
-	name := src.App
-	if p.config.Name != "" {
-		name = p.config.Name
+	name := p.appName(src, job)
+
+	previousID := readAppID(dir)
+	existing, err := p.findExistingApp(ctx, name, previousID, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// The previous app ID is only kept on the machine that deployed, so on
+	// a fresh runner the app can only be found by name.
+	if existing != nil && p.config.AppID == "" && previousID == "" {
+		u.Step(terminal.StatusWarn, fmt.Sprintf("No app ID from a previous deployment is recorded on this machine, "+
+			"so app %s (%s) was found by name. Set app_id to always deploy to it", name, existing.ID))
 	}
 
-	appID, err := p.findExistingApp(name, u)
+	// An app set by ID keeps its name unless one is configured.
+	if existing != nil && p.config.AppID != "" && p.config.Name == "" && p.config.NameTemplate == "" {
+		name = existing.Spec.Name
+	}
+
+	var base *godo.AppSpec
+	if p.config.SpecFile != "" {
+		u.Update("Loading app spec from " + p.config.SpecFile)
+		base, err = loadSpecFile(appPath(src, p.config.SpecFile), specVars(src, job, img, p.config.SpecVars))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spec, err := p.buildSpec(name, img, deployConfig, existing, base)
 	if err != nil {
 		return nil, err
 	}
 
-	registry, repository, regType := parseImage(img)
-
-	spec := &godo.AppSpec{
-		Name: name,
-		Services: []*godo.AppServiceSpec{
-			&godo.AppServiceSpec{
-				Name:             name,
-				InstanceSizeSlug: p.config.InstanceSizeSlug,
-				InstanceCount:    p.config.InstanceCount,
-				Image: &godo.ImageSourceSpec{
-					RegistryType: regType,
-					Registry:     registry,
-					Repository:   repository,
-					Tag:          img.Tag,
-				},
-				HTTPPort: p.config.HTTPPort,
-				Routes: []*godo.AppRouteSpec{
-					&godo.AppRouteSpec{
-						Path: p.config.Path,
-					},
-				},
-			},
-		},
+	spec.Region, err = appRegion(existing, stringOr(p.config.Region, spec.Region))
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the components built from the configuration are managed by
+	// Waypoint, so note them before anything from the live spec is merged in.
+	// Static sites don't run the image but are removed with the rest.
+	components := append(specComponents(spec), specStaticSites(spec)...)
+	databases := specDatabases(spec)
+
+	if existing != nil {
+		unmanaged := mergeUnmanaged(existing.Spec, spec, p.config.Merge)
+		if len(unmanaged) > 0 && p.config.Merge {
+			u.Step(terminal.StatusOK, fmt.Sprintf("Keeping items not managed by Waypoint: %s", strings.Join(unmanaged, ", ")))
+		} else if len(unmanaged) > 0 {
+			u.Step(terminal.StatusWarn, fmt.Sprintf("Removing items not managed by Waypoint: %s (set merge = true to keep them)",
+				strings.Join(unmanaged, ", ")))
+		}
+	}
+
+	u.Update("Validating app spec")
+	catalogue, err := p.fetchCatalogue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := catalogue.validate(spec); err != nil {
+		return nil, err
+	}
+
+	if err := p.checkCost(ctx, log, u, catalogue, spec, existing); err != nil {
+		return nil, err
+	}
+
+	if p.planEnabled() {
+		u.Close()
+
+		var current *godo.AppSpec
+		if existing != nil {
+			current = existing.Spec
+		}
+		outputPlan(ui, current, spec)
+
+		// Returning a deployment here would record one in Waypoint that
+		// was never made, so plan mode always ends the operation.
+		return nil, fmt.Errorf("plan mode is enabled, no changes were made to app %s", name)
 	}
 
 	app := &godo.App{}
-	if appID != "" {
-		u.Update(fmt.Sprintf("Creating new deployment for existing application: %s (%s)", name, appID))
+	if existing != nil {
+		u.Update(fmt.Sprintf("Creating new deployment for existing application: %s (%s)", name, existing.ID))
 		appUpdateRequest := &godo.AppUpdateRequest{Spec: spec}
-		app, _, err = p.client.Apps.Update(context.Background(), appID, appUpdateRequest)
+		app, _, err = p.client.Apps.Update(ctx, existing.ID, appUpdateRequest)
+		if ctx.Err() != nil {
+			return nil, stoppedWaiting(existing.ID, "", ctx.Err())
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -153,32 +351,191 @@ func (p *Platform) deploy(
 	} else {
 		u.Update(fmt.Sprintf("Creating new application: %s", name))
 		appCreateRequest := &godo.AppCreateRequest{Spec: spec}
-		app, _, err = p.client.Apps.Create(context.TODO(), appCreateRequest)
+		app, _, err = p.client.Apps.Create(ctx, appCreateRequest)
+		if ctx.Err() != nil {
+			return nil, stoppedWaiting("", "", ctx.Err())
+		}
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	u.Update("Waiting for deployment to finish")
-	app, err = p.waitForAppDeployment(app.ID, u)
+	if err := writeAppID(dir, app.ID); err != nil {
+		log.Warn("unable to record app ID for the next deployment", "error", err)
+	}
+
+	// App Platform returns the spec with secret values encrypted, so prefer
+	// it to the submitted spec, which may hold them in plain text.
+	submitted := spec
+	if app.Spec != nil {
+		submitted = app.Spec
+	}
+
+	hash, err := specHash(submitted)
 	if err != nil {
 		return nil, err
 	}
 
+	specJSON, err := json.Marshal(submitted)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding app spec: %s", err)
+	}
+
+	if p.config.SpecOutput != "" {
+		path := appPath(src, p.config.SpecOutput)
+		if err := writeSpec(submitted, path); err != nil {
+			return nil, err
+		}
+		u.Step(terminal.StatusOK, fmt.Sprintf("Wrote app spec to %s", path))
+	}
+
+	var active *godo.Deployment
+	if p.waitEnabled() {
+		// The deployment's progress is shown as a step group, which can't
+		// be used while the status is.
+		u.Close()
+		app, err = p.waitForAppDeployment(ctx, ui, app.ID)
+		if failed, ok := err.(*deploymentFailedError); ok && p.config.AutoRollback {
+			return nil, p.rollback(ctx, ui, failed, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		active = app.ActiveDeployment
+
+		u = ui.Status()
+		defer u.Close()
+	} else {
+		// The app's last deployment before it was updated, if any, must
+		// not be mistaken for the one just submitted.
+		var since time.Time
+		if existing != nil {
+			since = existing.LastDeploymentCreatedAt
+		}
+		active, err = p.submittedDeployment(ctx, app.ID, since)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registry, repository, _ := parseImage(img)
 	deployment := &Deployment{
-		AppId:              app.ID,
-		AppName:            name,
-		DefaultIngress:     app.DefaultIngress,
-		LiveUrl:            app.LiveURL,
-		ActiveDeploymentId: app.ActiveDeployment.ID,
+		AppId:           app.ID,
+		AppName:         name,
+		DefaultIngress:  app.DefaultIngress,
+		LiveUrl:         app.LiveURL,
+		Components:      components,
+		Databases:       databases,
+		SpecHash:        hash,
+		Region:          submitted.Region,
+		TierSlug:        app.TierSlug,
+		ImageRegistry:   registry,
+		ImageRepository: repository,
+		ImageTag:        img.Tag,
+		ImageDigest:     imageDigest(img),
+		Spec:            string(specJSON),
+	}
+	if app.Region != nil && app.Region.Slug != "" {
+		deployment.Region = app.Region.Slug
+	}
+	if active != nil {
+		deployment.ActiveDeploymentId = active.ID
+		deployment.Phase = string(active.Phase)
+		deployment.Cause = active.Cause
+		deployment.CreatedAt = formatTime(active.CreatedAt)
+		if active.Phase == godo.DeploymentPhase_Active {
+			deployment.FinishedAt = formatTime(active.PhaseLastUpdatedAt)
+		}
+		if active.TierSlug != "" {
+			deployment.TierSlug = active.TierSlug
+		}
 	}
 
-	u.Step(terminal.StatusOK, fmt.Sprintf("Created App Platform deployment %s for %s", deployment.ActiveDeploymentId, name))
-	ui.Output("\nDigitalOcean App Platform URL: %s", deployment.LiveUrl, terminal.WithSuccessStyle())
+	if !p.waitEnabled() {
+		u.Step(terminal.StatusOK, fmt.Sprintf("Submitted App Platform deployment %s for %s without waiting for it to finish",
+			deployment.ActiveDeploymentId, name))
+	} else {
+		u.Step(terminal.StatusOK, fmt.Sprintf("Created App Platform deployment %s for %s", deployment.ActiveDeploymentId, name))
+	}
+
+	if deployment.LiveUrl != "" {
+		ui.Output("\nDigitalOcean App Platform URL: %s", deployment.LiveUrl, terminal.WithSuccessStyle())
+	}
 
 	return deployment, nil
 }
 
+// TemplateData implements component.Template, making details of the
+// deployment available to other parts of the Waypoint configuration. It is
+// safe to call on a nil Deployment, in which case every value is empty.
+func (d *Deployment) TemplateData() map[string]interface{} {
+	data := map[string]interface{}{
+		"app_id":          d.GetAppId(),
+		"app_name":        d.GetAppName(),
+		"live_url":        d.GetLiveUrl(),
+		"default_ingress": d.GetDefaultIngress(),
+		"deployment_id":   d.GetActiveDeploymentId(),
+		"region":          d.GetRegion(),
+		"components":      map[string]string{},
+	}
+	if d == nil {
+		return data
+	}
+
+	base := d.LiveUrl
+	if base == "" {
+		base = d.DefaultIngress
+	}
+
+	var spec *godo.AppSpec
+	if d.Spec != "" {
+		spec = &godo.AppSpec{}
+		if err := json.Unmarshal([]byte(d.Spec), spec); err != nil {
+			spec = nil
+		}
+	}
+
+	data["components"] = componentURLs(base, spec, d.Components)
+	return data
+}
+
+// componentURLs returns the URL each service and static site in spec is
+// served from. Without a spec, as for deployments made before it was
+// recorded, a single component is assumed to be served from base.
+func componentURLs(base string, spec *godo.AppSpec, components []string) map[string]string {
+	urls := map[string]string{}
+	if base == "" {
+		return urls
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	if spec == nil {
+		if len(components) == 1 {
+			urls[components[0]] = base
+		}
+		return urls
+	}
+
+	single := len(spec.Services)+len(spec.StaticSites) == 1
+	add := func(name string, routes []*godo.AppRouteSpec) {
+		switch {
+		case len(routes) > 0:
+			urls[name] = strings.TrimSuffix(base+"/"+strings.TrimPrefix(routes[0].Path, "/"), "/")
+		case single:
+			urls[name] = base
+		}
+	}
+
+	for _, s := range spec.Services {
+		add(s.Name, s.Routes)
+	}
+	for _, s := range spec.StaticSites {
+		add(s.Name, s.Routes)
+	}
+
+	return urls
+}
+
 func parseImage(img *docker.Image) (registry string, repository string, regType godo.ImageSourceSpecRegistryType) {
 	repository = img.Image
 	regType = godo.ImageSourceSpecRegistryType("UNSPECIFIED")
@@ -202,13 +559,124 @@ func parseImage(img *docker.Image) (registry string, repository string, regType
 	return registry, repository, regType
 }
 
-func (p *Platform) findExistingApp(name string, u terminal.Status) (string, error) {
+// parseDuration parses a positive duration option, returning def if it isn't
+// set.
+func parseDuration(option, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as \"10s\" or \"30m\": %s", option, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be greater than zero", option)
+	}
+
+	return d, nil
+}
+
+// appPath resolves a relative path against the app's path.
+func appPath(src *component.Source, path string) string {
+	if filepath.IsAbs(path) || src.Path == "" {
+		return path
+	}
+
+	return filepath.Join(src.Path, path)
+}
+
+// specVars returns the variables that can be referenced in the spec file.
+func specVars(
+	src *component.Source,
+	job *component.JobInfo,
+	img *docker.Image,
+	vars map[string]string,
+) map[string]map[string]string {
+	registry, repository, _ := parseImage(img)
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	workspace := defaultWorkspace
+	if job != nil && job.Workspace != "" {
+		workspace = job.Workspace
+	}
+
+	return map[string]map[string]string{
+		"var": vars,
+		"app": {"name": src.App, "workspace": workspace},
+		"image": {
+			"name":       img.Image,
+			"registry":   registry,
+			"repository": repository,
+			"tag":        img.Tag,
+		},
+	}
+}
+
+// appRegion returns the region to deploy to. App Platform can't move an
+// existing app to another region, so a configured region that differs from
+// the existing app's is an error rather than being silently ignored.
+func appRegion(existing *godo.App, configured string) (string, error) {
+	if existing == nil {
+		return configured, nil
+	}
+
+	current := existing.Spec.Region
+	if existing.Region != nil && existing.Region.Slug != "" {
+		current = existing.Region.Slug
+	}
+
+	if configured != "" && current != "" && configured != current {
+		return "", fmt.Errorf("app %s (%s) is in region %q and can't be moved to region %q; "+
+			"destroy the app or remove the region setting to continue deploying to %q",
+			existing.Spec.Name, existing.ID, current, configured, current)
+	}
+
+	if current == "" {
+		return configured, nil
+	}
+
+	return current, nil
+}
+
+// findExistingApp returns the app to deploy to, or nil if there isn't one.
+// The app set by app_id is always used. Otherwise the app the previous
+// deployment went to is used, as long as it still exists with the same
+// name, and failing that the app is looked up by name. If several apps share
+// the name, none is picked since updating the wrong app could break it.
+func (p *Platform) findExistingApp(ctx context.Context, name, previousID string, u terminal.Status) (*godo.App, error) {
+	if p.config.AppID != "" {
+		app, _, err := p.client.Apps.Get(ctx, p.config.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading app (%s) set by app_id: %s", p.config.AppID, err)
+		}
+
+		u.Update(fmt.Sprintf("Using app %s set by app_id", app.ID))
+		return app, nil
+	}
+
+	if previousID != "" {
+		app, _, err := p.client.Apps.Get(ctx, previousID)
+		if err != nil && !isNotFound(err) {
+			return nil, fmt.Errorf("Error reading app (%s) from the previous deployment: %s", previousID, err)
+		}
+
+		// An app that has been deleted or renamed since is no longer
+		// the one to deploy to, so fall back to looking it up by name.
+		if err == nil && app.Spec != nil && app.Spec.Name == name {
+			u.Update(fmt.Sprintf("Found existing app for %s from the previous deployment, ID: %s", name, app.ID))
+			return app, nil
+		}
+	}
+
 	list := []*godo.App{}
 	opt := &godo.ListOptions{PerPage: 200, Page: 1}
 	for {
-		apps, resp, err := p.client.Apps.List(context.TODO(), opt)
+		apps, resp, err := p.client.Apps.List(ctx, opt)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		list = append(list, apps...)
@@ -219,80 +687,204 @@ func (p *Platform) findExistingApp(name string, u terminal.Status) (string, erro
 
 		page, err := resp.Links.CurrentPage()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		opt.Page = page + 1
 	}
 
-	var foundIDs []string
+	var found []*godo.App
 	for _, a := range list {
 		if a.Spec.Name == name {
-			foundIDs = append(foundIDs, a.ID)
+			found = append(found, a)
 		}
 	}
 
-	if len(foundIDs) >= 1 {
-		u.Update(fmt.Sprintf("Found existing app for %s, ID: %s", name, foundIDs[0]))
-		return foundIDs[0], nil
+	if len(found) > 1 {
+		return nil, ambiguousAppError(name, found)
+	}
+
+	if len(found) == 1 {
+		u.Update(fmt.Sprintf("Found existing app for %s, ID: %s", name, found[0].ID))
+		return found[0], nil
 	}
 
 	u.Update(fmt.Sprintf("No existing app found with name: %s", name))
-	return "", nil
+	return nil, nil
+}
+
+// ambiguousAppError returns the error for several apps sharing a name,
+// listing them so the right one can be set with app_id.
+func ambiguousAppError(name string, apps []*godo.App) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "found %d apps named %s, set app_id to the one to deploy to:\n", len(apps), name)
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  ID\tREGION\tCREATED")
+	for _, a := range apps {
+		region := a.Spec.Region
+		if a.Region != nil && a.Region.Slug != "" {
+			region = a.Region.Slug
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", a.ID, region, formatTime(a.CreatedAt))
+	}
+	w.Flush()
+
+	return errors.New(strings.TrimSuffix(b.String(), "\n"))
 }
 
-func (p *Platform) waitForAppDeployment(id string, u terminal.Status) (*godo.App, error) {
-	tickerInterval := 10 //10s
-	timeout := 1800      //1800s, 30min
-	n := 0
+// waitForAppDeployment waits for the app's in progress deployment to finish,
+// showing the progress of each of its steps. If the deployment fails, the
+// logs of the components that failed are shown. If ctx is cancelled it stops
+// waiting straight away, leaving the deployment running on App Platform, and
+// says which deployment was left in progress.
+//
+// The progress is shown as a step group, so no other output may be in use
+// while it waits.
+func (p *Platform) waitForAppDeployment(ctx context.Context, ui terminal.UI, id string) (*godo.App, error) {
+	r := newProgressRenderer(ui, fmt.Sprintf("Waiting for app (%s) deployment to start", id))
+
+	app, err := p.watchAppDeployment(ctx, id, r)
+	if err != nil {
+		r.close(terminal.StatusError, fmt.Sprintf("App (%s) deployment did not finish", id))
+		if failed, ok := err.(*deploymentFailedError); ok {
+			p.reportFailure(ctx, ui, failed)
+		}
+
+		return nil, err
+	}
+
+	r.close(terminal.StatusOK, fmt.Sprintf("App (%s) deployment (%s) is active", id, app.ActiveDeployment.ID))
+	return app, nil
+}
+
+// watchAppDeployment polls the app's in progress deployment until it
+// finishes, passing its progress to r.
+func (p *Platform) watchAppDeployment(ctx context.Context, id string, r *progressRenderer) (*godo.App, error) {
+	w := newPoller(p.pollInterval, p.waitTimeout)
 
 	var deploymentID string
-	ticker := time.NewTicker(time.Duration(tickerInterval) * time.Second)
-	for range ticker.C {
-		if n*tickerInterval > timeout {
-			ticker.Stop()
-			break
+	for {
+		if err := w.wait(ctx); err == errWaitTimeout {
+			return nil, stoppedWaiting(id, deploymentID, fmt.Errorf("timeout after %s", w.timeout))
+		} else if err != nil {
+			return nil, stoppedWaiting(id, deploymentID, err)
 		}
 
 		if deploymentID == "" {
-			app, _, err := p.client.Apps.Get(context.Background(), id)
-			if err != nil {
-				return nil, fmt.Errorf("Error trying to read app deployment state: %s", err)
+			app, resp, err := p.client.Apps.Get(ctx, id)
+			if ctx.Err() != nil {
+				return nil, stoppedWaiting(id, deploymentID, ctx.Err())
 			}
-
-			if app.InProgressDeployment != nil {
+			if ok, err := w.done(resp, err); err != nil {
+				return nil, fmt.Errorf("Error trying to read app deployment state: %s", err)
+			} else if ok && app.InProgressDeployment != nil {
 				deploymentID = app.InProgressDeployment.ID
 			}
 
-		} else {
-			deployment, _, err := p.client.Apps.GetDeployment(context.Background(), id, deploymentID)
+			continue
+		}
+
+		deployment, resp, err := p.client.Apps.GetDeployment(ctx, id, deploymentID)
+		if ctx.Err() != nil {
+			return nil, stoppedWaiting(id, deploymentID, ctx.Err())
+		}
+		if ok, err := w.done(resp, err); err != nil {
+			return nil, fmt.Errorf("Error trying to read app deployment state: %s", err)
+		} else if !ok {
+			r.status(fmt.Sprintf("Retrying reading app (%s) deployment (%s) state in %s", id, deploymentID, w.delay))
+			continue
+		}
+
+		r.update(deployment.Progress)
+
+		allSuccessful := deployment.Progress.SuccessSteps == deployment.Progress.TotalSteps
+		if allSuccessful {
+			app, _, err := p.client.Apps.Get(ctx, id)
 			if err != nil {
-				ticker.Stop()
 				return nil, fmt.Errorf("Error trying to read app deployment state: %s", err)
 			}
 
-			allSuccessful := deployment.Progress.SuccessSteps == deployment.Progress.TotalSteps
-			if allSuccessful {
-				app, _, err := p.client.Apps.Get(context.Background(), id)
-				if err != nil {
-					return nil, fmt.Errorf("Error trying to read app deployment state: %s", err)
-				}
+			return app, nil
+		}
 
-				ticker.Stop()
-				return app, nil
+		if deployment.Progress.ErrorSteps > 0 {
+			return nil, &deploymentFailedError{
+				appID:        id,
+				deploymentID: deployment.ID,
+				failures:     failedSteps(deployment.Progress),
 			}
+		}
 
-			if deployment.Progress.ErrorSteps > 0 {
-				ticker.Stop()
-				return nil, fmt.Errorf("error deploying app (%s) (deployment ID: %s):\n%s", id, deployment.ID, godo.Stringify(deployment.Progress))
-			}
+		r.status(fmt.Sprintf("Waiting for app (%s) deployment (%s) to become active. Phase: %s (%d/%d)",
+			id, deployment.ID, deployment.Phase, deployment.Progress.SuccessSteps, deployment.Progress.TotalSteps))
+	}
+}
+
+// submittedDeployment returns the deployment App Platform created for the
+// spec just submitted, which is the first created after since. App Platform
+// creates it shortly after the app is created or updated, so it's polled for
+// briefly. If it doesn't show up, nil is returned rather than an earlier
+// deployment.
+func (p *Platform) submittedDeployment(ctx context.Context, id string, since time.Time) (*godo.Deployment, error) {
+	w := newPoller(submittedPollInterval, submittedTimeout)
+	for {
+		d, resp, err := deploymentSince(ctx, p.client, id, since)
+		if ctx.Err() != nil {
+			return nil, stoppedWaiting(id, "", ctx.Err())
+		}
+		if ok, err := w.done(resp, err); err != nil {
+			return nil, fmt.Errorf("Error listing app (%s) deployments: %s", id, err)
+		} else if ok && d != nil {
+			return d, nil
+		}
 
-			u.Update(fmt.Sprintf("Waiting for app (%s) deployment (%s) to become active. Phase: %s (%d/%d)",
-				id, deployment.ID, deployment.Phase, deployment.Progress.SuccessSteps, deployment.Progress.TotalSteps))
+		if err := w.wait(ctx); err == errWaitTimeout {
+			return nil, nil
+		} else if err != nil {
+			return nil, stoppedWaiting(id, "", err)
 		}
+	}
+}
+
+// imageDigest returns the digest of an image referenced by digest rather
+// than by tag, or an empty string.
+func imageDigest(img *docker.Image) string {
+	if i := strings.Index(img.Image, "@"); i >= 0 {
+		return img.Image[i+1:]
+	}
+	if strings.HasPrefix(img.Tag, "sha256:") {
+		return img.Tag
+	}
+
+	return ""
+}
+
+// formatTime formats t as RFC 3339, or returns an empty string if it's unset.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// stoppedWaiting returns the error for giving up on a deployment because the
+// operation was cancelled or timed out. App Platform carries on with the
+// deployment, so the error says which one was left in progress. An empty
+// appID means the app was being created.
+func stoppedWaiting(appID, deploymentID string, err error) error {
+	if appID == "" {
+		return fmt.Errorf("%s: stopped waiting for the app to be created; "+
+			"it may have been created anyway, in which case the next deployment goes to it", err)
+	}
 
-		n++
+	if deploymentID == "" {
+		return fmt.Errorf("%s: stopped waiting for app (%s) to start deploying; "+
+			"a deployment may still be in progress", err, appID)
 	}
 
-	return nil, fmt.Errorf("timeout waiting to app (%s) deployment", id)
+	return fmt.Errorf("%s: stopped waiting for app (%s) deployment (%s), which was left in progress",
+		err, appID, deploymentID)
 }
@@ -1,9 +1,18 @@
 package platform
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
 	"github.com/hashicorp/waypoint/builtin/docker"
 )
 
@@ -49,3 +58,219 @@ func TestParseImage(t *testing.T) {
 		})
 	}
 }
+
+func TestAppRegion(t *testing.T) {
+	existing := &godo.App{
+		ID:     "6c1a8e5d",
+		Spec:   &godo.AppSpec{Name: "example"},
+		Region: &godo.AppRegion{Slug: "ams"},
+	}
+
+	tests := []struct {
+		name       string
+		existing   *godo.App
+		configured string
+		want       string
+		err        bool
+	}{
+		{"new app", nil, "nyc", "nyc", false},
+		{"new app default region", nil, "", "", false},
+		{"existing app keeps region", existing, "", "ams", false},
+		{"existing app same region", existing, "ams", "ams", false},
+		{"existing app different region", existing, "nyc", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := appRegion(tt.existing, tt.configured)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForAppDeploymentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := &Platform{client: godo.NewFromToken("")}
+	_, err := p.waitForAppDeployment(ctx, terminal.ConsoleUI(ctx), "app-id")
+	if err == nil || !strings.Contains(err.Error(), "app (app-id)") || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStoppedWaiting(t *testing.T) {
+	tests := []struct {
+		name         string
+		appID        string
+		deploymentID string
+		want         string
+	}{
+		{"creating", "", "", "context canceled: stopped waiting for the app to be created; " +
+			"it may have been created anyway, in which case the next deployment goes to it"},
+		{"updating", "app-id", "", "context canceled: stopped waiting for app (app-id) to start deploying; " +
+			"a deployment may still be in progress"},
+		{"deploying", "app-id", "dep-id", "context canceled: stopped waiting for app (app-id) deployment (dep-id), " +
+			"which was left in progress"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stoppedWaiting(tt.appID, tt.deploymentID, context.Canceled).Error(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	tests := []struct {
+		image *docker.Image
+		want  string
+	}{
+		{&docker.Image{Image: "registry.digitalocean.com/foo/bar", Tag: "v1"}, ""},
+		{&docker.Image{Image: "foo/bar@sha256:abc"}, "sha256:abc"},
+		{&docker.Image{Image: "foo/bar", Tag: "sha256:def"}, "sha256:def"},
+	}
+
+	for _, tt := range tests {
+		if got := imageDigest(tt.image); got != tt.want {
+			t.Errorf("imageDigest(%s:%s): got %q, want %q", tt.image.Image, tt.image.Tag, got, tt.want)
+		}
+	}
+}
+
+func TestDeploymentTemplateData(t *testing.T) {
+	var nilDeployment *Deployment
+	data := nilDeployment.TemplateData()
+	for _, key := range []string{"app_id", "app_name", "live_url", "default_ingress", "deployment_id", "region", "components"} {
+		if _, ok := data[key]; !ok {
+			t.Errorf("nil deployment is missing %q", key)
+		}
+	}
+
+	d := &Deployment{
+		AppId:              "app-id",
+		AppName:            "sample",
+		LiveUrl:            "https://sample.ondigitalocean.app",
+		ActiveDeploymentId: "dep-id",
+		Region:             "nyc",
+		Spec: `{"name": "sample", "services": [
+			{"name": "web", "routes": [{"path": "/"}]},
+			{"name": "api", "routes": [{"path": "/api"}]},
+			{"name": "internal"}
+		]}`,
+	}
+
+	data = d.TemplateData()
+	if data["app_id"] != "app-id" || data["deployment_id"] != "dep-id" || data["region"] != "nyc" {
+		t.Errorf("unexpected template data: %v", data)
+	}
+
+	want := map[string]string{
+		"web": "https://sample.ondigitalocean.app",
+		"api": "https://sample.ondigitalocean.app/api",
+	}
+	if got := data["components"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got components %v, want %v", got, want)
+	}
+}
+
+func TestComponentURLsWithoutSpec(t *testing.T) {
+	got := componentURLs("https://sample.ondigitalocean.app/", nil, []string{"sample"})
+	if want := map[string]string{"sample": "https://sample.ondigitalocean.app"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAmbiguousAppError(t *testing.T) {
+	created := time.Date(2020, 12, 1, 10, 0, 0, 0, time.UTC)
+	apps := []*godo.App{
+		{ID: "app-1", Spec: &godo.AppSpec{Name: "sample"}, Region: &godo.AppRegion{Slug: "nyc"}, CreatedAt: created},
+		{ID: "app-2", Spec: &godo.AppSpec{Name: "sample", Region: "ams"}, CreatedAt: created.Add(time.Hour)},
+	}
+
+	want := "found 2 apps named sample, set app_id to the one to deploy to:\n" +
+		"  ID     REGION  CREATED\n" +
+		"  app-1  nyc     2020-12-01T10:00:00Z\n" +
+		"  app-2  ams     2020-12-01T11:00:00Z"
+	if got := ambiguousAppError("sample", apps).Error(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAppIDState(t *testing.T) {
+	path, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	dir := &datadir.App{Dir: datadir.NewBasicDir(filepath.Join(path, "cache"), filepath.Join(path, "data"))}
+
+	if id := readAppID(dir); id != "" {
+		t.Errorf("got %q before any deployment, want none", id)
+	}
+	if err := writeAppID(dir, "app-id"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id := readAppID(dir); id != "app-id" {
+		t.Errorf("got %q, want app-id", id)
+	}
+	if id := readAppID(nil); id != "" {
+		t.Errorf("got %q without a data directory, want none", id)
+	}
+}
+
+func TestTokenState(t *testing.T) {
+	path, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	dir := &datadir.Project{Dir: datadir.NewBasicDir(filepath.Join(path, "cache"), filepath.Join(path, "data"))}
+
+	if token := readToken(dir); token != "" {
+		t.Errorf("got %q before authenticating, want none", token)
+	}
+
+	// An existing file readable by others is made private when written.
+	if err := os.MkdirAll(dir.DataDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir.DataDir(), tokenFile), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeToken(dir, "token"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token := readToken(dir); token != "token" {
+		t.Errorf("got %q, want token", token)
+	}
+
+	info, err := os.Stat(filepath.Join(dir.DataDir(), tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("got mode %o, want 600", mode)
+	}
+
+	if err := writeToken(nil, "token"); err == nil {
+		t.Error("expected an error without a data directory")
+	}
+}
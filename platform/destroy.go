@@ -2,10 +2,23 @@ package platform
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
 	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
 )
 
+const (
+	// deletionPollInterval and deletionTimeout pace the wait for an app to
+	// be deleted.
+	deletionPollInterval = 5 * time.Second
+	deletionTimeout      = 5 * time.Minute
+)
+
 // DestroyFunc implements the Destroyer interface
 func (p *Platform) DestroyFunc() interface{} {
 	return p.destroy
@@ -38,8 +51,179 @@ func (p *Platform) DestroyFunc() interface{} {
 //
 // If an error is returned, Waypoint stops the execution flow and
 // returns an error to the user.
-func (p *Platform) destroy(ctx context.Context, ui terminal.UI, deployment *Deployment) error {
-	// This destroys a deployment. DO App Platform only has one active
-	// deployment at a time. We don't want to run a destroy here.
+func (p *Platform) destroy(ctx context.Context, ui terminal.UI, dir *datadir.Project, deployment *Deployment) error {
+	p.useStoredToken(dir)
+
+	u := ui.Status()
+	defer u.Close()
+	u.Update(fmt.Sprintf("Destroying App Platform app: %s (%s)", deployment.AppName, deployment.AppId))
+
+	app, _, err := p.client.Apps.Get(ctx, deployment.AppId)
+	if err != nil {
+		if isNotFound(err) {
+			u.Step(terminal.StatusWarn, fmt.Sprintf("App %s (%s) no longer exists, nothing to destroy", deployment.AppName, deployment.AppId))
+			return nil
+		}
+
+		return fmt.Errorf("Error trying to read app (%s): %s", deployment.AppId, err)
+	}
+
+	// DO App Platform only has one active deployment at a time. If this
+	// deployment has since been replaced, destroying it must not take down
+	// the app that is now serving traffic. The app may also have been
+	// redeployed outside of Waypoint, so say how to delete it regardless.
+	if app.ActiveDeployment != nil && deployment.ActiveDeploymentId != "" &&
+		app.ActiveDeployment.ID != deployment.ActiveDeploymentId {
+		u.Step(terminal.StatusWarn, fmt.Sprintf("Deployment %s has been superseded by %s, leaving app %s in place. "+
+			"Destroying the workspace with `waypoint destroy` deletes it, or run `doctl apps delete %s`",
+			deployment.ActiveDeploymentId, app.ActiveDeployment.ID, deployment.AppId, deployment.AppId))
+		return nil
+	}
+
+	// Deployments made before component names were recorded only ever
+	// contained a single service named after the app.
+	managed := deployment.Components
+	if len(managed) == 0 {
+		managed = []string{deployment.AppName}
+	}
+	managed = append(managed, deployment.Databases...)
+
+	if app.Spec != nil && componentCount(app.Spec) > 0 {
+		removed := removeComponents(app.Spec, managed)
+		if componentCount(app.Spec) > 0 {
+			if !removed {
+				u.Step(terminal.StatusWarn, fmt.Sprintf("App %s no longer contains components %s, leaving it in place",
+					deployment.AppId, strings.Join(managed, ", ")))
+				return nil
+			}
+
+			u.Update(fmt.Sprintf("Removing components %s from app %s", strings.Join(managed, ", "), deployment.AppId))
+			app, _, err = p.client.Apps.Update(ctx, deployment.AppId, &godo.AppUpdateRequest{Spec: app.Spec})
+			if err != nil {
+				return fmt.Errorf("Error removing components from app (%s): %s", deployment.AppId, err)
+			}
+
+			u.Close()
+			if _, err := p.waitForAppDeployment(ctx, ui, app.ID); err != nil {
+				return err
+			}
+
+			u = ui.Status()
+			defer u.Close()
+
+			u.Step(terminal.StatusOK, fmt.Sprintf("Removed components %s from app %s", strings.Join(managed, ", "), deployment.AppId))
+			return nil
+		}
+	}
+
+	_, err = p.client.Apps.Delete(ctx, deployment.AppId)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("Error deleting app (%s): %s", deployment.AppId, err)
+	}
+
+	u.Update(fmt.Sprintf("Waiting for app %s (%s) to be deleted", deployment.AppName, deployment.AppId))
+	if err := p.waitForAppDeletion(ctx, deployment.AppId); err != nil {
+		return err
+	}
+
+	u.Step(terminal.StatusOK, fmt.Sprintf("Deleted App Platform app %s (%s)", deployment.AppName, deployment.AppId))
 	return nil
 }
+
+// waitForAppDeletion polls the app until the API no longer returns it,
+// retrying transient errors like the waits for deployments do.
+func (p *Platform) waitForAppDeletion(ctx context.Context, id string) error {
+	w := newPoller(deletionPollInterval, deletionTimeout)
+	for {
+		_, resp, err := p.client.Apps.Get(ctx, id)
+		if isNotFound(err) {
+			return nil
+		}
+		if _, err := w.done(resp, err); err != nil {
+			return fmt.Errorf("Error trying to read app (%s) state: %s", id, err)
+		}
+
+		if err := w.wait(ctx); err == errWaitTimeout {
+			return fmt.Errorf("timeout waiting for app (%s) to be deleted", id)
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// componentCount returns the number of components defined in an app spec.
+func componentCount(spec *godo.AppSpec) int {
+	return len(spec.Services) + len(spec.StaticSites) + len(spec.Workers) +
+		len(spec.Jobs) + len(spec.Databases)
+}
+
+// removeComponents removes the named services, static sites, workers, jobs
+// and databases from the spec, reporting whether any were present.
+func removeComponents(spec *godo.AppSpec, names []string) bool {
+	remove := map[string]bool{}
+	for _, n := range names {
+		remove[n] = true
+	}
+
+	removed := false
+	services := spec.Services[:0]
+	for _, s := range spec.Services {
+		if remove[s.Name] {
+			removed = true
+			continue
+		}
+		services = append(services, s)
+	}
+	spec.Services = services
+
+	sites := spec.StaticSites[:0]
+	for _, s := range spec.StaticSites {
+		if remove[s.Name] {
+			removed = true
+			continue
+		}
+		sites = append(sites, s)
+	}
+	spec.StaticSites = sites
+
+	workers := spec.Workers[:0]
+	for _, w := range spec.Workers {
+		if remove[w.Name] {
+			removed = true
+			continue
+		}
+		workers = append(workers, w)
+	}
+	spec.Workers = workers
+
+	jobs := spec.Jobs[:0]
+	for _, j := range spec.Jobs {
+		if remove[j.Name] {
+			removed = true
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	spec.Jobs = jobs
+
+	databases := spec.Databases[:0]
+	for _, d := range spec.Databases {
+		if remove[d.Name] {
+			removed = true
+			continue
+		}
+		databases = append(databases, d)
+	}
+	spec.Databases = databases
+
+	return removed
+}
+
+// isNotFound reports whether err is a 404 response from the DigitalOcean API.
+func isNotFound(err error) bool {
+	if e, ok := err.(*godo.ErrorResponse); ok {
+		return e.Response != nil && e.Response.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestRemoveComponents(t *testing.T) {
+	spec := &godo.AppSpec{
+		Services:  []*godo.AppServiceSpec{{Name: "web"}, {Name: "api"}},
+		Workers:   []*godo.AppWorkerSpec{{Name: "queue"}},
+		Jobs:      []*godo.AppJobSpec{{Name: "migrate"}},
+		Databases: []*godo.AppDatabaseSpec{{Name: "db"}},
+	}
+
+	if got := componentCount(spec); got != 5 {
+		t.Fatalf("got %d components, want 5", got)
+	}
+
+	if removeComponents(spec, []string{"missing"}) {
+		t.Errorf("removed a component that does not exist")
+	}
+
+	if !removeComponents(spec, []string{"web", "queue", "migrate"}) {
+		t.Fatalf("expected components to be removed")
+	}
+
+	if len(spec.Services) != 1 || spec.Services[0].Name != "api" {
+		t.Errorf("got services %s, want [api]", godo.Stringify(spec.Services))
+	}
+	if len(spec.Workers) != 0 || len(spec.Jobs) != 0 {
+		t.Errorf("expected workers and jobs to be removed")
+	}
+	if got := componentCount(spec); got != 2 {
+		t.Errorf("got %d components, want 2", got)
+	}
+}
+
+func TestRemoveComponentsSpecFile(t *testing.T) {
+	path := writeSpecFile(t, "app.yaml", `
+name: sample
+services:
+- name: api
+static_sites:
+- name: frontend
+databases:
+- name: db
+`)
+
+	spec, err := loadSpecFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components := append(specComponents(spec), specStaticSites(spec)...)
+	if want := []string{"api", "frontend"}; !reflect.DeepEqual(components, want) {
+		t.Fatalf("got components %v, want %v", components, want)
+	}
+
+	if !removeComponents(spec, append(components, specDatabases(spec)...)) {
+		t.Fatalf("expected components to be removed")
+	}
+	if got := componentCount(spec); got != 0 {
+		t.Errorf("got %d components left, want 0", got)
+	}
+}
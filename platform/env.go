@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+)
+
+// secretEntrypointEnvs are the entrypoint variables that hold credentials and
+// are stored as App Platform secrets.
+var secretEntrypointEnvs = map[string]bool{
+	"WAYPOINT_CEB_INVITE_TOKEN": true,
+}
+
+// entrypointEnvs are the variables the Waypoint entrypoint configuration may
+// add. Some of them change with every deployment.
+var entrypointEnvs = map[string]bool{
+	"WAYPOINT_DEPLOYMENT_ID":          true,
+	"WAYPOINT_SERVER_DISABLE":         true,
+	"WAYPOINT_SERVER_ADDR":            true,
+	"WAYPOINT_SERVER_TLS":             true,
+	"WAYPOINT_SERVER_TLS_SKIP_VERIFY": true,
+	"WAYPOINT_CEB_INVITE_TOKEN":       true,
+}
+
+// EnvConfig holds configuration for an environment variable
+type EnvConfig struct {
+	Key   string `hcl:"key,label"`
+	Value string `hcl:"value,optional"`
+	Scope string `hcl:"scope,optional"`
+}
+
+// validateEnvs normalizes the scope of each variable and checks for
+// duplicate keys across the env and secret_env blocks.
+func validateEnvs(envs, secrets []*EnvConfig) error {
+	seen := map[string]bool{}
+	for _, e := range append(append([]*EnvConfig{}, envs...), secrets...) {
+		if seen[e.Key] {
+			return fmt.Errorf("environment variable %q is defined more than once", e.Key)
+		}
+		seen[e.Key] = true
+
+		switch scope := godo.AppVariableScope(strings.ToUpper(e.Scope)); scope {
+		case "":
+		case godo.AppVariableScope_RunTime, godo.AppVariableScope_BuildTime, godo.AppVariableScope_RunAndBuildTime:
+			e.Scope = string(scope)
+		default:
+			return fmt.Errorf("environment variable %q has invalid scope %q, must be one of: %s, %s, %s",
+				e.Key, e.Scope, godo.AppVariableScope_RunTime, godo.AppVariableScope_BuildTime,
+				godo.AppVariableScope_RunAndBuildTime)
+		}
+	}
+
+	return nil
+}
+
+// buildEnvs converts the env and secret_env blocks to App Platform
+// variable definitions.
+func buildEnvs(envs, secrets []*EnvConfig) []*godo.AppVariableDefinition {
+	var defs []*godo.AppVariableDefinition
+	for _, e := range envs {
+		defs = append(defs, &godo.AppVariableDefinition{
+			Key:   e.Key,
+			Value: e.Value,
+			Scope: godo.AppVariableScope(e.Scope),
+			Type:  godo.AppVariableType_General,
+		})
+	}
+
+	for _, e := range secrets {
+		defs = append(defs, &godo.AppVariableDefinition{
+			Key:   e.Key,
+			Value: e.Value,
+			Scope: godo.AppVariableScope(e.Scope),
+			Type:  godo.AppVariableType_Secret,
+		})
+	}
+
+	return defs
+}
+
+// overlayEnvs returns base with any variables in overlay replacing those
+// with the same key.
+func overlayEnvs(base, overlay []*godo.AppVariableDefinition) []*godo.AppVariableDefinition {
+	overridden := map[string]bool{}
+	for _, o := range overlay {
+		overridden[o.Key] = true
+	}
+
+	var defs []*godo.AppVariableDefinition
+	for _, b := range base {
+		if !overridden[b.Key] {
+			defs = append(defs, b)
+		}
+	}
+
+	return append(defs, overlay...)
+}
+
+// mergeEnvs carries the encrypted values of existing secrets across to the
+// desired variables. The API only ever returns secrets in encrypted form, so
+// a secret configured without a value keeps whatever value the app already
+// has rather than being blanked. A value that is set explicitly, including
+// a previously encrypted value copied from the app spec, is sent as-is.
+func mergeEnvs(existing, desired []*godo.AppVariableDefinition) ([]*godo.AppVariableDefinition, error) {
+	current := map[string]*godo.AppVariableDefinition{}
+	for _, e := range existing {
+		current[e.Key] = e
+	}
+
+	var merged []*godo.AppVariableDefinition
+	for _, d := range desired {
+		if d.Type != godo.AppVariableType_Secret || d.Value != "" {
+			merged = append(merged, d)
+			continue
+		}
+
+		e, ok := current[d.Key]
+		if !ok || e.Type != godo.AppVariableType_Secret {
+			return nil, fmt.Errorf("secret %q has no value configured and is not yet set on the app", d.Key)
+		}
+
+		merged = append(merged, &godo.AppVariableDefinition{
+			Key:   d.Key,
+			Value: e.Value,
+			Scope: d.Scope,
+			Type:  godo.AppVariableType_Secret,
+		})
+	}
+
+	return merged, nil
+}
+
+// appendEntrypointEnvs adds the variables the Waypoint entrypoint needs to
+// connect back to the server. Variables that are already defined are left as
+// they are so that they can be overridden from the configuration.
+func appendEntrypointEnvs(defs []*godo.AppVariableDefinition, config *component.DeploymentConfig) []*godo.AppVariableDefinition {
+	defined := map[string]bool{}
+	for _, d := range defs {
+		defined[d.Key] = true
+	}
+
+	env := config.Env()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if defined[k] {
+			continue
+		}
+
+		typ := godo.AppVariableType_General
+		if secretEntrypointEnvs[k] {
+			typ = godo.AppVariableType_Secret
+		}
+
+		defs = append(defs, &godo.AppVariableDefinition{
+			Key:   k,
+			Value: env[k],
+			Scope: godo.AppVariableScope_RunTime,
+			Type:  typ,
+		})
+	}
+
+	return defs
+}
@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+)
+
+func TestValidateEnvs(t *testing.T) {
+	envs := []*EnvConfig{{Key: "LOG_LEVEL", Value: "debug", Scope: "run_time"}}
+	secrets := []*EnvConfig{{Key: "API_KEY", Value: "hunter2"}}
+
+	if err := validateEnvs(envs, secrets); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if envs[0].Scope != string(godo.AppVariableScope_RunTime) {
+		t.Errorf("got scope %q, want RUN_TIME", envs[0].Scope)
+	}
+
+	if err := validateEnvs([]*EnvConfig{{Key: "FOO", Scope: "sometimes"}}, nil); err == nil {
+		t.Errorf("expected an error for an invalid scope")
+	}
+
+	if err := validateEnvs([]*EnvConfig{{Key: "FOO"}}, []*EnvConfig{{Key: "FOO"}}); err == nil {
+		t.Errorf("expected an error for a duplicate key")
+	}
+}
+
+func TestMergeEnvs(t *testing.T) {
+	existing := []*godo.AppVariableDefinition{
+		{Key: "API_KEY", Value: "EV[1:abc:def]", Type: godo.AppVariableType_Secret},
+		{Key: "DB_PASSWORD", Value: "EV[1:ghi:jkl]", Type: godo.AppVariableType_Secret},
+		{Key: "LOG_LEVEL", Value: "info", Type: godo.AppVariableType_General},
+	}
+
+	desired := buildEnvs(
+		[]*EnvConfig{{Key: "LOG_LEVEL", Value: "debug"}},
+		[]*EnvConfig{{Key: "API_KEY"}, {Key: "DB_PASSWORD", Value: "changed"}},
+	)
+
+	merged, err := mergeEnvs(existing, desired)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string]string{
+		"LOG_LEVEL":   "debug",
+		"API_KEY":     "EV[1:abc:def]",
+		"DB_PASSWORD": "changed",
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("got %d variables, want %d", len(merged), len(want))
+	}
+	for _, m := range merged {
+		if m.Value != want[m.Key] {
+			t.Errorf("%s: got %q, want %q", m.Key, m.Value, want[m.Key])
+		}
+	}
+
+	_, err = mergeEnvs(nil, buildEnvs(nil, []*EnvConfig{{Key: "API_KEY"}}))
+	if err == nil {
+		t.Errorf("expected an error for a secret with no value")
+	}
+}
+
+func TestAppendEntrypointEnvs(t *testing.T) {
+	config := &component.DeploymentConfig{
+		Id:                    "01EQ8Y2X5M3K",
+		ServerAddr:            "waypoint.example.com:9701",
+		ServerTls:             true,
+		EntrypointInviteToken: "token",
+	}
+
+	defs := buildEnvs([]*EnvConfig{{Key: "WAYPOINT_SERVER_ADDR", Value: "override:9701"}}, nil)
+	defs = appendEntrypointEnvs(defs, config)
+
+	got := map[string]*godo.AppVariableDefinition{}
+	for _, d := range defs {
+		got[d.Key] = d
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("got %d variables, want 4: %s", len(got), godo.Stringify(defs))
+	}
+	if got["WAYPOINT_SERVER_ADDR"].Value != "override:9701" {
+		t.Errorf("configured variable was overridden: %q", got["WAYPOINT_SERVER_ADDR"].Value)
+	}
+	if got["WAYPOINT_DEPLOYMENT_ID"].Value != config.Id {
+		t.Errorf("got deployment ID %q, want %q", got["WAYPOINT_DEPLOYMENT_ID"].Value, config.Id)
+	}
+	if got["WAYPOINT_CEB_INVITE_TOKEN"].Type != godo.AppVariableType_Secret {
+		t.Errorf("invite token should be stored as a secret")
+	}
+	if got["WAYPOINT_SERVER_TLS"].Type != godo.AppVariableType_General {
+		t.Errorf("got type %q for WAYPOINT_SERVER_TLS, want GENERAL", got["WAYPOINT_SERVER_TLS"].Type)
+	}
+}
@@ -0,0 +1,186 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// failureLogLines is the number of lines of logs shown for each component
+// that failed to deploy.
+const failureLogLines = 20
+
+// deploymentFailedError is returned when a deployment fails. Its message
+// summarizes which steps failed and why.
+type deploymentFailedError struct {
+	appID        string
+	deploymentID string
+	failures     []stepFailure
+}
+
+func (e *deploymentFailedError) Error() string {
+	msg := fmt.Sprintf("error deploying app (%s) (deployment ID: %s)", e.appID, e.deploymentID)
+	if len(e.failures) == 0 {
+		return msg
+	}
+
+	var reasons []string
+	for _, f := range e.failures {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", f.Step, f.Reason))
+	}
+
+	return msg + ": " + strings.Join(reasons, "; ")
+}
+
+// stepFailure is a failed step of a deployment.
+type stepFailure struct {
+	Component string
+	Step      string
+	Reason    string
+	LogType   godo.AppLogType
+}
+
+// failedSteps returns the failed steps of a deployment, one for each
+// component and stage that failed. Steps under the build stage have build
+// logs and the rest have deploy logs.
+func failedSteps(progress *godo.DeploymentProgress) []stepFailure {
+	if progress == nil {
+		return nil
+	}
+
+	var failures []stepFailure
+	seen := map[string]int{}
+
+	// Steps within a component's step don't always name the component, so
+	// it is passed down from the step that does.
+	var walk func(steps []*godo.DeploymentProgressStep, logType godo.AppLogType, component string)
+	walk = func(steps []*godo.DeploymentProgressStep, logType godo.AppLogType, component string) {
+		for _, s := range steps {
+			c := component
+			if s.ComponentName != "" {
+				c = s.ComponentName
+			}
+
+			if s.Status == godo.DeploymentProgressStepStatus_Error {
+				f := stepFailure{
+					Component: c,
+					Step:      stepName(s),
+					Reason:    stepReason(s),
+					LogType:   logType,
+				}
+
+				key := f.Component + "/" + string(f.LogType)
+				if i, ok := seen[key]; !ok {
+					seen[key] = len(failures)
+					failures = append(failures, f)
+				} else if failures[i].Reason == "failed" && f.Reason != "failed" {
+					// The reason is usually only set on the innermost
+					// step, so prefer it to the step that contains it.
+					failures[i].Reason = f.Reason
+				}
+			}
+
+			walk(s.Steps, logType, c)
+		}
+	}
+
+	for _, s := range progress.Steps {
+		logType := godo.AppLogTypeDeploy
+		if strings.Contains(strings.ToLower(s.Name), "build") {
+			logType = godo.AppLogTypeBuild
+		}
+		walk([]*godo.DeploymentProgressStep{s}, logType, "")
+	}
+
+	// A failure of a stage as a whole only adds to failures of the
+	// components within it.
+	if len(failures) > 1 {
+		var components []stepFailure
+		for _, f := range failures {
+			if f.Component != "" {
+				components = append(components, f)
+			}
+		}
+		if len(components) > 0 {
+			failures = components
+		}
+	}
+
+	return failures
+}
+
+func stepReason(s *godo.DeploymentProgressStep) string {
+	switch {
+	case s.Reason == nil:
+		return "failed"
+	case s.Reason.Message != "":
+		return s.Reason.Message
+	case s.Reason.Code != "":
+		return s.Reason.Code
+	}
+
+	return "failed"
+}
+
+// reportFailure shows why each failed step of a deployment failed along
+// with the last lines of the failed component's build or deploy logs.
+func (p *Platform) reportFailure(ctx context.Context, ui terminal.UI, e *deploymentFailedError) {
+	for _, f := range e.failures {
+		ui.Output("%s failed: %s", f.Step, f.Reason, terminal.WithErrorStyle())
+		if f.Component == "" {
+			continue
+		}
+
+		lines, err := p.logTail(ctx, e.appID, e.deploymentID, f.Component, f.LogType, failureLogLines)
+		if err != nil {
+			ui.Output("Unable to read %s logs for %s: %s", strings.ToLower(string(f.LogType)), f.Component, err,
+				terminal.WithWarningStyle())
+			continue
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		ui.Output("Last %d lines of %s logs for %s:", len(lines), strings.ToLower(string(f.LogType)), f.Component,
+			terminal.WithHeaderStyle())
+		for _, line := range lines {
+			ui.Output("%s", line, terminal.WithInfoStyle())
+		}
+	}
+}
+
+// logTail returns the last n lines of a component's logs for a deployment.
+func (p *Platform) logTail(
+	ctx context.Context,
+	appID, deploymentID, component string,
+	logType godo.AppLogType,
+	n int,
+) ([]string, error) {
+	body, err := openLogs(ctx, p.client, appID, deploymentID, component, logType, false)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return tailLines(body, n)
+}
+
+// tailLines returns the last n lines read from r.
+func tailLines(r io.Reader, n int) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+
+	return lines, scanner.Err()
+}
@@ -0,0 +1,93 @@
+package platform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestFailedSteps(t *testing.T) {
+	progress := &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{
+			{
+				Name:   "build",
+				Status: godo.DeploymentProgressStepStatus_Error,
+				Steps: []*godo.DeploymentProgressStep{
+					{
+						Name:          "web",
+						MessageBase:   "Building service",
+						ComponentName: "web",
+						Status:        godo.DeploymentProgressStepStatus_Error,
+						Steps: []*godo.DeploymentProgressStep{{
+							Name:   "buildpack",
+							Status: godo.DeploymentProgressStepStatus_Error,
+							Reason: &godo.DeploymentProgressStepReason{Code: "BuildJobFailed", Message: "npm install failed"},
+						}},
+					},
+					{
+						Name:          "worker",
+						MessageBase:   "Building worker",
+						ComponentName: "worker",
+						Status:        godo.DeploymentProgressStepStatus_Success,
+					},
+				},
+			},
+			{
+				Name:   "deploy",
+				Status: godo.DeploymentProgressStepStatus_Error,
+				Steps: []*godo.DeploymentProgressStep{{
+					Name:          "api",
+					MessageBase:   "Deploying service",
+					ComponentName: "api",
+					Status:        godo.DeploymentProgressStepStatus_Error,
+					Reason:        &godo.DeploymentProgressStepReason{Code: "HealthChecksFailed"},
+				}},
+			},
+		},
+	}
+
+	want := []stepFailure{
+		{Component: "web", Step: "Building service web", Reason: "npm install failed", LogType: godo.AppLogTypeBuild},
+		{Component: "api", Step: "Deploying service api", Reason: "HealthChecksFailed", LogType: godo.AppLogTypeDeploy},
+	}
+
+	got := failedSteps(progress)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+
+	err := &deploymentFailedError{appID: "app-id", deploymentID: "dep-id", failures: got}
+	wantErr := "error deploying app (app-id) (deployment ID: dep-id): " +
+		"Building service web: npm install failed; Deploying service api: HealthChecksFailed"
+	if err.Error() != wantErr {
+		t.Errorf("got error %q, want %q", err.Error(), wantErr)
+	}
+}
+
+func TestFailedStepsStage(t *testing.T) {
+	progress := &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{{
+			Name:   "deploy",
+			Status: godo.DeploymentProgressStepStatus_Error,
+			Reason: &godo.DeploymentProgressStepReason{Message: "quota exceeded"},
+		}},
+	}
+
+	got := failedSteps(progress)
+	if len(got) != 1 || got[0].Component != "" || got[0].Reason != "quota exceeded" {
+		t.Errorf("unexpected failures: %#v", got)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	got, err := tailLines(strings.NewReader("one\ntwo\nthree\nfour\n"), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := []string{"three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+)
+
+const (
+	// maxLogBatch is the largest number of log events returned in a single
+	// batch.
+	maxLogBatch = 100
+
+	// logIdleTimeout is how long the log stream is kept open once nothing
+	// is waiting on NextLogBatch. Each call gets its own context, which
+	// ends when the call returns, so a caller that stops asking for logs
+	// can only be noticed by it not calling again.
+	logIdleTimeout = time.Minute
+)
+
+// LogsFunc implements LogPlatform
+func (p *Platform) LogsFunc() interface{} {
+	return p.logs
+}
+
+// A LogsFunc does not have a strict signature, you can define the parameters
+// you need based on the Available parameters that the Waypoint SDK provides.
+// Waypoint will automatically inject parameters as specified
+// in the signature at run time.
+//
+// In addition to default input parameters the Deployment from the DeployFunc step
+// can also be injected.
+//
+// The output parameters for LogsFunc must be a component.LogViewer and an error.
+func (p *Platform) logs(
+	ctx context.Context,
+	log hclog.Logger,
+	dir *datadir.Project,
+	deployment *Deployment,
+) (component.LogViewer, error) {
+	p.useStoredToken(dir)
+
+	return &LogViewer{
+		client:       p.client,
+		log:          log,
+		appID:        deployment.AppId,
+		deploymentID: deployment.ActiveDeploymentId,
+	}, nil
+}
+
+// LogViewer streams the run time logs of an App Platform deployment.
+type LogViewer struct {
+	client       *godo.Client
+	log          hclog.Logger
+	appID        string
+	deploymentID string
+
+	once   sync.Once
+	cancel context.CancelFunc
+	events chan component.LogEvent
+	err    error
+
+	// active is the number of NextLogBatch calls in progress and last is
+	// when the last one returned.
+	mu     sync.Mutex
+	active int
+	last   time.Time
+}
+
+// NextLogBatch implements component.LogViewer
+func (lv *LogViewer) NextLogBatch(ctx context.Context) ([]component.LogEvent, error) {
+	lv.once.Do(func() {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		lv.cancel = cancel
+		lv.events = make(chan component.LogEvent, maxLogBatch)
+		go lv.stream(streamCtx)
+		go lv.watch(streamCtx, logIdleTimeout)
+	})
+
+	lv.begin()
+	defer lv.end()
+
+	var batch []component.LogEvent
+	select {
+	case <-ctx.Done():
+		lv.cancel()
+		return nil, ctx.Err()
+	case ev, ok := <-lv.events:
+		if !ok {
+			return nil, lv.err
+		}
+		batch = append(batch, ev)
+	}
+
+	for len(batch) < maxLogBatch {
+		select {
+		case ev, ok := <-lv.events:
+			if !ok {
+				return batch, nil
+			}
+			batch = append(batch, ev)
+		default:
+			return batch, nil
+		}
+	}
+
+	return batch, nil
+}
+
+// begin records that a NextLogBatch call is in progress.
+func (lv *LogViewer) begin() {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.active++
+}
+
+// end records that a NextLogBatch call has returned.
+func (lv *LogViewer) end() {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.active--
+	lv.last = time.Now()
+}
+
+// idle reports whether no NextLogBatch call has been in progress for at
+// least timeout.
+func (lv *LogViewer) idle(now time.Time, timeout time.Duration) bool {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	return lv.active == 0 && now.Sub(lv.last) >= timeout
+}
+
+// watch stops the stream once the caller has stopped asking for logs for
+// timeout, so that the stream doesn't outlive a caller that went away
+// without cancelling a call.
+func (lv *LogViewer) watch(ctx context.Context, timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if lv.idle(now, timeout) {
+				lv.log.Debug("no longer asked for logs, closing stream", "app", lv.appID, "deployment", lv.deploymentID)
+				lv.cancel()
+				return
+			}
+		}
+	}
+}
+
+// stream follows the live log URL for the deployment, reconnecting whenever
+// the server closes the stream, until ctx is cancelled or an error occurs.
+func (lv *LogViewer) stream(ctx context.Context) {
+	defer close(lv.events)
+
+	for {
+		err := lv.follow(ctx)
+		if ctx.Err() != nil {
+			lv.err = ctx.Err()
+			return
+		}
+		if err != nil {
+			lv.err = err
+			return
+		}
+
+		lv.log.Debug("log stream closed, reconnecting", "app", lv.appID, "deployment", lv.deploymentID)
+		select {
+		case <-ctx.Done():
+			lv.err = ctx.Err()
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (lv *LogViewer) follow(ctx context.Context) error {
+	body, err := openLogs(ctx, lv.client, lv.appID, lv.deploymentID, "", godo.AppLogTypeRun, true)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return fmt.Errorf("no live logs are available for app (%s) deployment (%s)", lv.appID, lv.deploymentID)
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+
+		select {
+		case lv.events <- parseLogLine(scanner.Text()):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return scanner.Err()
+}
+
+// openLogs opens a component's logs for a deployment, or the logs of every
+// component if component is empty. With follow, the live log stream is
+// opened. Otherwise the first historic log is, falling back to the live
+// logs. It returns nil if there are no logs to open.
+func openLogs(
+	ctx context.Context,
+	client *godo.Client,
+	appID, deploymentID, component string,
+	logType godo.AppLogType,
+	follow bool,
+) (io.ReadCloser, error) {
+	logs, _, err := client.Apps.GetLogs(ctx, appID, deploymentID, component, logType, follow)
+	if err != nil {
+		return nil, fmt.Errorf("Error trying to read app (%s) logs: %s", appID, err)
+	}
+
+	url := logs.LiveURL
+	if !follow && len(logs.HistoricURLs) > 0 {
+		url = logs.HistoricURLs[0]
+	}
+	if url == "" {
+		return nil, nil
+	}
+
+	// The log URLs are pre-signed, so they are fetched with a plain HTTP
+	// client rather than one that would send our API token along with them.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error trying to read app (%s) logs: %s", appID, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// parseLogLine parses a line from the App Platform log stream. Lines are of
+// the form "<component> [<instance>] <timestamp> <message>". Lines that do not
+// match are returned as-is, timestamped with the current time.
+func parseLogLine(line string) component.LogEvent {
+	fields := strings.SplitN(line, " ", 4)
+
+	for i := 1; i < len(fields) && i <= 2; i++ {
+		ts, err := time.Parse(time.RFC3339Nano, fields[i])
+		if err != nil {
+			continue
+		}
+
+		partition := strings.Join(fields[:i], "/")
+		message := strings.Join(fields[i+1:], " ")
+		return component.LogEvent{
+			Partition: partition,
+			Timestamp: ts,
+			Message:   message,
+		}
+	}
+
+	return component.LogEvent{
+		Timestamp: time.Now(),
+		Message:   line,
+	}
+}
@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func TestParseLogLine(t *testing.T) {
+	ts := time.Date(2020, 11, 19, 20, 51, 9, 853913745, time.UTC)
+
+	tests := []struct {
+		line      string
+		partition string
+		message   string
+		timestamp time.Time
+	}{
+		{
+			"web 2020-11-19T20:51:09.853913745Z listening on :8080",
+			"web", "listening on :8080", ts,
+		},
+		{
+			"web web-5d8f7c9b4-x2v7k 2020-11-19T20:51:09.853913745Z GET / 200",
+			"web/web-5d8f7c9b4-x2v7k", "GET / 200", ts,
+		},
+		{
+			"web 2020-11-19T20:51:09.853913745Z",
+			"web", "", ts,
+		},
+		{
+			"not a log line",
+			"", "not a log line", time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			ev := parseLogLine(tt.line)
+			if ev.Partition != tt.partition {
+				t.Errorf("got partition %q, want %q", ev.Partition, tt.partition)
+			}
+			if ev.Message != tt.message {
+				t.Errorf("got message %q, want %q", ev.Message, tt.message)
+			}
+			if !tt.timestamp.IsZero() && !ev.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("got timestamp %s, want %s", ev.Timestamp, tt.timestamp)
+			}
+		})
+	}
+}
+
+func TestLogViewerIdle(t *testing.T) {
+	lv := &LogViewer{}
+	now := time.Now()
+
+	lv.begin()
+	if lv.idle(now.Add(time.Hour), time.Minute) {
+		t.Error("idle while a call is in progress")
+	}
+
+	lv.end()
+	if lv.idle(time.Now(), time.Minute) {
+		t.Error("idle right after a call returned")
+	}
+	if !lv.idle(time.Now().Add(2*time.Minute), time.Minute) {
+		t.Error("not idle long after the last call returned")
+	}
+}
+
+func TestLogViewerWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lv := &LogViewer{log: hclog.NewNullLogger(), cancel: cancel, last: time.Now()}
+	go lv.watch(ctx, 20*time.Millisecond)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream not stopped once the caller stopped asking for logs")
+	}
+}
@@ -0,0 +1,166 @@
+package platform
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/godo"
+)
+
+// mergeUnmanaged finds everything in the live spec that desired doesn't
+// contain: components Waypoint doesn't manage and variables set on managed
+// components outside of Waypoint. If keep is true they are added to desired
+// so that updating the app leaves them in place. Either way, a description
+// of each is returned so the user can be told what is kept or removed.
+//
+// Domains are always kept since they are managed by the release rather than
+// the deployment.
+func mergeUnmanaged(live, desired *godo.AppSpec, keep bool) []string {
+	if live == nil {
+		return nil
+	}
+
+	desired.Domains = mergeDomainSpecs(live.Domains, desired.Domains)
+
+	var items []string
+	add := func(desc string) {
+		items = append(items, desc)
+	}
+	envFound := func(kind, name string) func(string) {
+		return func(key string) {
+			add(fmt.Sprintf("env %s on %s %s", key, kind, name))
+		}
+	}
+
+	for _, s := range live.Services {
+		if d := desiredService(desired, s.Name); d != nil {
+			d.Envs = mergeUnmanagedEnvs(s.Envs, d.Envs, keep, envFound("service", s.Name))
+			continue
+		}
+
+		add("service " + s.Name)
+		if keep {
+			desired.Services = append(desired.Services, s)
+		}
+	}
+
+	for _, w := range live.Workers {
+		if d := desiredWorker(desired, w.Name); d != nil {
+			d.Envs = mergeUnmanagedEnvs(w.Envs, d.Envs, keep, envFound("worker", w.Name))
+			continue
+		}
+
+		add("worker " + w.Name)
+		if keep {
+			desired.Workers = append(desired.Workers, w)
+		}
+	}
+
+	for _, j := range live.Jobs {
+		if d := desiredJob(desired, j.Name); d != nil {
+			d.Envs = mergeUnmanagedEnvs(j.Envs, d.Envs, keep, envFound("job", j.Name))
+			continue
+		}
+
+		add("job " + j.Name)
+		if keep {
+			desired.Jobs = append(desired.Jobs, j)
+		}
+	}
+
+	for _, s := range live.StaticSites {
+		found := false
+		for _, d := range desired.StaticSites {
+			found = found || d.Name == s.Name
+		}
+		if found {
+			continue
+		}
+
+		add("static site " + s.Name)
+		if keep {
+			desired.StaticSites = append(desired.StaticSites, s)
+		}
+	}
+
+	for _, db := range live.Databases {
+		found := false
+		for _, d := range desired.Databases {
+			found = found || d.Name == db.Name
+		}
+		if found {
+			continue
+		}
+
+		add("database " + db.Name)
+		if keep {
+			desired.Databases = append(desired.Databases, db)
+		}
+	}
+
+	return items
+}
+
+// mergeUnmanagedEnvs calls found for each live variable that isn't in
+// desired and, if keep is true, adds it to desired.
+func mergeUnmanagedEnvs(live, desired []*godo.AppVariableDefinition, keep bool, found func(key string)) []*godo.AppVariableDefinition {
+	defined := map[string]bool{}
+	for _, d := range desired {
+		defined[d.Key] = true
+	}
+
+	for _, l := range live {
+		if defined[l.Key] {
+			continue
+		}
+
+		found(l.Key)
+		if keep {
+			desired = append(desired, l)
+		}
+	}
+
+	return desired
+}
+
+// mergeDomainSpecs adds the live domains that aren't in desired.
+func mergeDomainSpecs(live, desired []*godo.AppDomainSpec) []*godo.AppDomainSpec {
+	defined := map[string]bool{}
+	for _, d := range desired {
+		defined[d.Domain] = true
+	}
+
+	for _, l := range live {
+		if !defined[l.Domain] {
+			desired = append(desired, l)
+		}
+	}
+
+	return desired
+}
+
+func desiredService(spec *godo.AppSpec, name string) *godo.AppServiceSpec {
+	for _, s := range spec.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func desiredWorker(spec *godo.AppSpec, name string) *godo.AppWorkerSpec {
+	for _, w := range spec.Workers {
+		if w.Name == name {
+			return w
+		}
+	}
+	return nil
+}
+
+func desiredJob(spec *godo.AppSpec, name string) *godo.AppJobSpec {
+	for _, j := range spec.Jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func testLiveSpec() *godo.AppSpec {
+	return &godo.AppSpec{
+		Name: "example",
+		Services: []*godo.AppServiceSpec{
+			{
+				Name: "web",
+				Envs: []*godo.AppVariableDefinition{
+					{Key: "LOG_LEVEL", Value: "info"},
+					{Key: "FEATURE_FLAG", Value: "on"},
+				},
+			},
+			{Name: "admin"},
+		},
+		Workers:   []*godo.AppWorkerSpec{{Name: "queue"}},
+		Databases: []*godo.AppDatabaseSpec{{Name: "db"}},
+		Domains:   []*godo.AppDomainSpec{{Domain: "example.com"}},
+	}
+}
+
+func testDesiredSpec() *godo.AppSpec {
+	return &godo.AppSpec{
+		Name: "example",
+		Services: []*godo.AppServiceSpec{{
+			Name: "web",
+			Envs: []*godo.AppVariableDefinition{{Key: "LOG_LEVEL", Value: "debug"}},
+		}},
+	}
+}
+
+func TestMergeUnmanaged(t *testing.T) {
+	want := []string{"env FEATURE_FLAG on service web", "service admin", "worker queue", "database db"}
+
+	t.Run("keep", func(t *testing.T) {
+		desired := testDesiredSpec()
+		got := mergeUnmanaged(testLiveSpec(), desired, true)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		if componentCount(desired) != 4 {
+			t.Errorf("got %d components, want 4", componentCount(desired))
+		}
+		envs := desired.Services[0].Envs
+		if len(envs) != 2 || envs[0].Value != "debug" || envs[1].Key != "FEATURE_FLAG" {
+			t.Errorf("unexpected envs: %s", godo.Stringify(envs))
+		}
+		if len(desired.Domains) != 1 {
+			t.Errorf("expected domains to be kept")
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		desired := testDesiredSpec()
+		got := mergeUnmanaged(testLiveSpec(), desired, false)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		if componentCount(desired) != 1 || len(desired.Services[0].Envs) != 1 {
+			t.Errorf("unmanaged items were kept: %s", godo.Stringify(desired))
+		}
+		if len(desired.Domains) != 1 {
+			t.Errorf("domains should be kept even when not merging")
+		}
+	})
+
+	t.Run("new app", func(t *testing.T) {
+		if got := mergeUnmanaged(nil, testDesiredSpec(), true); len(got) != 0 {
+			t.Errorf("got %v for a new app, want nothing", got)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package platform
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// planEnvVar enables plan mode without changing the configuration.
+const planEnvVar = "WAYPOINT_DIGITALOCEAN_PLAN"
+
+// maskedValue is shown in place of secret values.
+const maskedValue = "(secret)"
+
+// componentExists marks a component that is being added or removed.
+const componentExists = "(exists)"
+
+// secretResubmitted marks a secret whose new value is submitted but can't be
+// compared with the encrypted value App Platform returns for it.
+const secretResubmitted = "re-submitted (can't compare)"
+
+// encryptedPrefix starts the encrypted form in which App Platform returns
+// secret values.
+const encryptedPrefix = "EV["
+
+// fieldValue is the value of a single field in a flattened spec.
+type fieldValue struct {
+	Value  string
+	Secret bool
+}
+
+// display returns the value to show the user, masking secrets.
+func (v fieldValue) display() string {
+	if v.Secret && v.Value != "" {
+		return maskedValue
+	}
+
+	return v.Value
+}
+
+// encrypted reports whether the value is a secret in the encrypted form App
+// Platform returns, which can't be compared with a value in plain text.
+func (v fieldValue) encrypted() bool {
+	return v.Secret && strings.HasPrefix(v.Value, encryptedPrefix)
+}
+
+// specChange is a single difference between two app specs.
+type specChange struct {
+	Component string
+	Field     string
+	Current   string
+	Desired   string
+}
+
+// planEnabled reports whether deploy should only show the changes it would
+// make.
+func (p *Platform) planEnabled() bool {
+	if p.config.Plan {
+		return true
+	}
+
+	enabled, _ := strconv.ParseBool(os.Getenv(planEnvVar))
+	return enabled
+}
+
+// outputPlan prints the changes between the current and desired specs.
+func outputPlan(ui terminal.UI, current, desired *godo.AppSpec) {
+	var changes []specChange
+	var resubmitted []string
+	for _, c := range diffSpecs(current, desired) {
+		if c.Desired == secretResubmitted {
+			resubmitted = append(resubmitted, c.Component+" "+c.Field)
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	if len(resubmitted) > 0 {
+		ui.Output("Secrets re-submitted, which can't be compared with their encrypted values: %s",
+			strings.Join(resubmitted, ", "), terminal.WithInfoStyle())
+	}
+
+	if len(changes) == 0 {
+		ui.Output("No changes to the app spec", terminal.WithSuccessStyle())
+		return
+	}
+
+	ui.Output("Planned changes to app %s:", desired.Name, terminal.WithHeaderStyle())
+
+	tbl := terminal.NewTable("Component", "Field", "Current", "Desired")
+	for _, c := range changes {
+		color := terminal.Yellow
+		switch {
+		case c.Current == "":
+			color = terminal.Green
+		case c.Desired == "":
+			color = terminal.Red
+		}
+
+		tbl.Rich(
+			[]string{c.Component, c.Field, c.Current, c.Desired},
+			[]string{"", "", color, color},
+		)
+	}
+
+	ui.Table(tbl)
+}
+
+// diffSpecs returns the differences between two app specs, grouped by
+// component. A nil current spec is treated as an empty app.
+func diffSpecs(current, desired *godo.AppSpec) []specChange {
+	cur := flattenSpec(current)
+	des := flattenSpec(desired)
+
+	components := map[string]bool{}
+	for c := range cur {
+		components[c] = true
+	}
+	for c := range des {
+		components[c] = true
+	}
+
+	var names []string
+	for c := range components {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+
+	var changes []specChange
+	for _, c := range names {
+		_, inCur := cur[c]
+		_, inDes := des[c]
+		switch {
+		case c == "app":
+		case inCur && !inDes:
+			changes = append(changes, specChange{Component: c, Current: componentExists})
+		case !inCur && inDes:
+			changes = append(changes, specChange{Component: c, Desired: componentExists})
+		}
+
+		fields := map[string]bool{}
+		for f := range cur[c] {
+			fields[f] = true
+		}
+		for f := range des[c] {
+			fields[f] = true
+		}
+
+		var keys []string
+		for f := range fields {
+			keys = append(keys, f)
+		}
+		sort.Strings(keys)
+
+		for _, f := range keys {
+			curVal, desVal := cur[c][f], des[c][f]
+			if curVal.Value == desVal.Value {
+				continue
+			}
+
+			change := specChange{
+				Component: c,
+				Field:     f,
+				Current:   curVal.display(),
+				Desired:   desVal.display(),
+			}
+			if curVal.encrypted() && desVal.Secret && desVal.Value != "" && !desVal.encrypted() {
+				change.Desired = secretResubmitted
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// flattenSpec maps each component of spec to its fields and their values as
+// strings. Secret values are kept so that changes to them can be detected,
+// but are marked so that they are never displayed.
+func flattenSpec(spec *godo.AppSpec) map[string]map[string]fieldValue {
+	out := map[string]map[string]fieldValue{}
+	if spec == nil {
+		return out
+	}
+
+	app := map[string]fieldValue{}
+	setField(app, "region", spec.Region)
+	for _, d := range spec.Domains {
+		v := string(d.Type)
+		if d.Wildcard {
+			v += ", wildcard"
+		}
+		if d.Zone != "" {
+			v += ", zone " + d.Zone
+		}
+		setField(app, "domain "+d.Domain, v)
+	}
+	out["app"] = app
+
+	for _, s := range spec.Services {
+		f := map[string]fieldValue{}
+		setField(f, "image", imageString(s.Image))
+		setField(f, "instance_size_slug", s.InstanceSizeSlug)
+		setIntField(f, "instance_count", s.InstanceCount)
+		setField(f, "run_command", s.RunCommand)
+		setIntField(f, "http_port", s.HTTPPort)
+
+		var routes []string
+		for _, r := range s.Routes {
+			routes = append(routes, r.Path)
+		}
+		setField(f, "routes", strings.Join(routes, ", "))
+
+		var ports []string
+		for _, port := range s.InternalPorts {
+			ports = append(ports, strconv.FormatInt(port, 10))
+		}
+		setField(f, "internal_ports", strings.Join(ports, ", "))
+
+		if s.HealthCheck != nil {
+			setField(f, "health_check", godo.Stringify(s.HealthCheck))
+		}
+		if s.CORS != nil {
+			setField(f, "cors", godo.Stringify(s.CORS))
+		}
+
+		setEnvFields(f, s.Envs)
+		out["service "+s.Name] = f
+	}
+
+	for _, w := range spec.Workers {
+		f := map[string]fieldValue{}
+		setField(f, "image", imageString(w.Image))
+		setField(f, "instance_size_slug", w.InstanceSizeSlug)
+		setIntField(f, "instance_count", w.InstanceCount)
+		setField(f, "run_command", w.RunCommand)
+		setEnvFields(f, w.Envs)
+		out["worker "+w.Name] = f
+	}
+
+	for _, j := range spec.Jobs {
+		f := map[string]fieldValue{}
+		setField(f, "image", imageString(j.Image))
+		setField(f, "kind", string(j.Kind))
+		setField(f, "instance_size_slug", j.InstanceSizeSlug)
+		setIntField(f, "instance_count", j.InstanceCount)
+		setField(f, "run_command", j.RunCommand)
+		setEnvFields(f, j.Envs)
+		out["job "+j.Name] = f
+	}
+
+	for _, s := range spec.StaticSites {
+		f := map[string]fieldValue{}
+		setField(f, "source_dir", s.SourceDir)
+		setField(f, "output_dir", s.OutputDir)
+		setEnvFields(f, s.Envs)
+		out["static_site "+s.Name] = f
+	}
+
+	for _, d := range spec.Databases {
+		f := map[string]fieldValue{}
+		setField(f, "engine", string(d.Engine))
+		setField(f, "version", d.Version)
+		setField(f, "cluster_name", d.ClusterName)
+		setField(f, "db_name", d.DBName)
+		setField(f, "db_user", d.DBUser)
+		if d.Production {
+			setField(f, "production", "true")
+		}
+		out["database "+d.Name] = f
+	}
+
+	return out
+}
+
+func setField(fields map[string]fieldValue, key, value string) {
+	if value != "" {
+		fields[key] = fieldValue{Value: value}
+	}
+}
+
+func setIntField(fields map[string]fieldValue, key string, value int64) {
+	if value != 0 {
+		fields[key] = fieldValue{Value: strconv.FormatInt(value, 10)}
+	}
+}
+
+// setEnvFields adds the variables to fields, leaving out the entrypoint
+// variables since some of them change with every deployment.
+func setEnvFields(fields map[string]fieldValue, envs []*godo.AppVariableDefinition) {
+	for _, e := range envs {
+		if entrypointEnvs[e.Key] {
+			continue
+		}
+
+		fields["env "+e.Key] = fieldValue{
+			Value:  e.Value,
+			Secret: e.Type == godo.AppVariableType_Secret,
+		}
+		setField(fields, "env "+e.Key+" scope", string(e.Scope))
+	}
+}
+
+func imageString(img *godo.ImageSourceSpec) string {
+	if img == nil {
+		return ""
+	}
+
+	s := img.Repository
+	if img.Registry != "" {
+		s = img.Registry + "/" + s
+	}
+	if img.Tag != "" {
+		s += ":" + img.Tag
+	}
+
+	return s
+}
@@ -0,0 +1,136 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestDiffSpecs(t *testing.T) {
+	current := &godo.AppSpec{
+		Name:   "example",
+		Region: "ams",
+		Services: []*godo.AppServiceSpec{{
+			Name:          "web",
+			Image:         &godo.ImageSourceSpec{Repository: "example", Tag: "v1"},
+			InstanceCount: 1,
+			Routes:        []*godo.AppRouteSpec{{Path: "/"}},
+			Envs: []*godo.AppVariableDefinition{
+				{Key: "API_KEY", Value: "EV[1:abc:def]", Type: godo.AppVariableType_Secret},
+				{Key: "TOKEN", Value: "EV[1:ghi:jkl]", Type: godo.AppVariableType_Secret},
+			},
+		}},
+		Workers: []*godo.AppWorkerSpec{{Name: "queue"}},
+	}
+
+	desired := &godo.AppSpec{
+		Name:   "example",
+		Region: "ams",
+		Services: []*godo.AppServiceSpec{{
+			Name:          "web",
+			Image:         &godo.ImageSourceSpec{Repository: "example", Tag: "v2"},
+			InstanceCount: 1,
+			Routes:        []*godo.AppRouteSpec{{Path: "/"}, {Path: "/api"}},
+			Envs: []*godo.AppVariableDefinition{
+				{Key: "API_KEY", Value: "EV[1:abc:def]", Type: godo.AppVariableType_Secret},
+				{Key: "TOKEN", Value: "hunter2", Type: godo.AppVariableType_Secret},
+			},
+		}},
+		Domains: []*godo.AppDomainSpec{{Domain: "example.com", Type: godo.AppDomainSpecType_Primary}},
+	}
+
+	want := []specChange{
+		{"app", "domain example.com", "", "PRIMARY"},
+		{"service web", "env TOKEN", maskedValue, secretResubmitted},
+		{"service web", "image", "example:v1", "example:v2"},
+		{"service web", "routes", "/", "/, /api"},
+		{"worker queue", "", componentExists, ""},
+	}
+
+	got := diffSpecs(current, desired)
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, c := range got {
+		if c.Current == "hunter2" || c.Desired == "hunter2" {
+			t.Errorf("secret value was not masked: %+v", c)
+		}
+	}
+}
+
+func TestDiffSpecsNewApp(t *testing.T) {
+	desired := &godo.AppSpec{
+		Name:     "example",
+		Services: []*godo.AppServiceSpec{{Name: "web", InstanceSizeSlug: "basic-xxs"}},
+	}
+
+	got := diffSpecs(nil, desired)
+	if len(got) != 2 || got[0].Desired != componentExists || got[1].Desired != "basic-xxs" {
+		t.Errorf("unexpected changes: %+v", got)
+	}
+}
+
+func TestDiffSpecsUnchanged(t *testing.T) {
+	spec := func(deploymentID, token string) *godo.AppSpec {
+		return &godo.AppSpec{
+			Name: "example",
+			Services: []*godo.AppServiceSpec{{
+				Name: "web",
+				Envs: []*godo.AppVariableDefinition{
+					{Key: "WAYPOINT_DEPLOYMENT_ID", Value: deploymentID},
+					{Key: "WAYPOINT_CEB_INVITE_TOKEN", Value: token, Type: godo.AppVariableType_Secret},
+				},
+			}},
+		}
+	}
+
+	if got := diffSpecs(spec("01ABC", "EV[1:abc:def]"), spec("01DEF", "invite")); len(got) != 0 {
+		t.Errorf("got changes to the entrypoint variables: %+v", got)
+	}
+}
+
+func TestDiffSpecsSecrets(t *testing.T) {
+	spec := func(envs ...*godo.AppVariableDefinition) *godo.AppSpec {
+		return &godo.AppSpec{Name: "example", Services: []*godo.AppServiceSpec{{Name: "web", Envs: envs}}}
+	}
+	secret := func(value string) *godo.AppVariableDefinition {
+		return &godo.AppVariableDefinition{Key: "API_KEY", Value: value, Type: godo.AppVariableType_Secret}
+	}
+
+	tests := []struct {
+		name    string
+		current *godo.AppSpec
+		desired *godo.AppSpec
+		want    []specChange
+	}{
+		{"unchanged encrypted value", spec(secret("EV[1:abc]")), spec(secret("EV[1:abc]")), nil},
+		{"encrypted value", spec(secret("EV[1:abc]")), spec(secret("hunter2")),
+			[]specChange{{"service web", "env API_KEY", maskedValue, secretResubmitted}}},
+		{"changed plain value", spec(secret("hunter1")), spec(secret("hunter2")),
+			[]specChange{{"service web", "env API_KEY", maskedValue, maskedValue}}},
+		{"added", spec(), spec(secret("hunter2")),
+			[]specChange{{"service web", "env API_KEY", "", maskedValue}}},
+		{"removed", spec(secret("EV[1:abc]")), spec(),
+			[]specChange{{"service web", "env API_KEY", maskedValue, ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffSpecs(tt.current, tt.desired)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// maxProgressDepth limits how far into the tree of deployment steps is
+// shown. Below the per-component steps the tree only holds fine grained
+// detail that makes the output hard to follow.
+const maxProgressDepth = 3
+
+// progressLine is a single step of a deployment as it is shown to the user.
+type progressLine struct {
+	Key     string
+	Message string
+	Status  godo.DeploymentProgressStepStatus
+}
+
+// progressRenderer shows the progress of a deployment as a step group, with
+// a step for the deployment as a whole followed by a step for each
+// deployment step that has started.
+type progressRenderer struct {
+	sg      terminal.StepGroup
+	overall terminal.Step
+	steps   map[string]terminal.Step
+	done    map[string]bool
+}
+
+func newProgressRenderer(ui terminal.UI, msg string) *progressRenderer {
+	sg := ui.StepGroup()
+	return &progressRenderer{
+		sg:      sg,
+		overall: sg.Add(msg),
+		steps:   map[string]terminal.Step{},
+		done:    map[string]bool{},
+	}
+}
+
+// status updates the message of the step for the deployment as a whole.
+func (r *progressRenderer) status(msg string) {
+	r.overall.Update(msg)
+}
+
+// update shows the current state of each of the deployment's steps.
+func (r *progressRenderer) update(progress *godo.DeploymentProgress) {
+	for _, line := range flattenProgress(progress, time.Now()) {
+		if r.done[line.Key] {
+			continue
+		}
+
+		step, ok := r.steps[line.Key]
+		if !ok {
+			if line.Status == godo.DeploymentProgressStepStatus_Pending ||
+				line.Status == godo.DeploymentProgressStepStatus_Unknown {
+				continue
+			}
+
+			step = r.sg.Add(line.Message)
+			r.steps[line.Key] = step
+		}
+
+		step.Update(line.Message)
+		switch line.Status {
+		case godo.DeploymentProgressStepStatus_Success:
+			step.Status(terminal.StatusOK)
+			step.Done()
+			r.done[line.Key] = true
+		case godo.DeploymentProgressStepStatus_Error:
+			step.Status(terminal.StatusError)
+			step.Done()
+			r.done[line.Key] = true
+		}
+	}
+}
+
+// close finishes the step for the deployment as a whole with status and
+// msg, marks any steps that haven't finished as left in progress, and waits
+// for the step group to complete.
+func (r *progressRenderer) close(status, msg string) {
+	for key, step := range r.steps {
+		if !r.done[key] {
+			step.Status(terminal.StatusWarn)
+			step.Done()
+		}
+	}
+
+	r.overall.Update(msg)
+	r.overall.Status(status)
+	r.overall.Done()
+	r.sg.Wait()
+}
+
+// flattenProgress returns the steps of a deployment in the order they are
+// shown, indented by their depth in the tree, with how long each has taken
+// and why any failed. The summary steps are used if the full tree isn't
+// available.
+func flattenProgress(progress *godo.DeploymentProgress, now time.Time) []progressLine {
+	if progress == nil {
+		return nil
+	}
+
+	steps := progress.Steps
+	if len(steps) == 0 {
+		steps = progress.SummarySteps
+	}
+
+	var lines []progressLine
+	var walk func(steps []*godo.DeploymentProgressStep, parent string, depth int)
+	walk = func(steps []*godo.DeploymentProgressStep, parent string, depth int) {
+		if depth >= maxProgressDepth {
+			return
+		}
+
+		for _, s := range steps {
+			key := parent + "/" + stepName(s)
+			lines = append(lines, progressLine{
+				Key:     key,
+				Message: strings.Repeat("  ", depth) + stepMessage(s, now),
+				Status:  s.Status,
+			})
+			walk(s.Steps, key, depth+1)
+		}
+	}
+	walk(steps, "", 0)
+
+	return lines
+}
+
+// stepName returns a readable name for a deployment step.
+func stepName(s *godo.DeploymentProgressStep) string {
+	switch {
+	case s.MessageBase != "" && s.ComponentName != "":
+		return s.MessageBase + " " + s.ComponentName
+	case s.MessageBase != "":
+		return s.MessageBase
+	}
+
+	return s.Name
+}
+
+// stepMessage describes a deployment step with how long it has taken and,
+// if it failed, why.
+func stepMessage(s *godo.DeploymentProgressStep, now time.Time) string {
+	msg := stepName(s)
+	if s.StartedAt.IsZero() {
+		return msg
+	}
+
+	end := s.EndedAt
+	if end.IsZero() {
+		end = now
+	}
+	msg = fmt.Sprintf("%s (%s)", msg, end.Sub(s.StartedAt).Round(time.Second))
+
+	if s.Status == godo.DeploymentProgressStepStatus_Error && s.Reason != nil && s.Reason.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, s.Reason.Message)
+	}
+
+	return msg
+}
@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestFlattenProgress(t *testing.T) {
+	start := time.Date(2020, 12, 1, 10, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Minute)
+
+	progress := &godo.DeploymentProgress{
+		Steps: []*godo.DeploymentProgressStep{
+			{
+				Name:      "build",
+				Status:    godo.DeploymentProgressStepStatus_Success,
+				StartedAt: start,
+				EndedAt:   start.Add(65 * time.Second),
+				Steps: []*godo.DeploymentProgressStep{{
+					Name:          "web",
+					MessageBase:   "Building service",
+					ComponentName: "web",
+					Status:        godo.DeploymentProgressStepStatus_Success,
+					Steps: []*godo.DeploymentProgressStep{{
+						Name: "detail",
+						Steps: []*godo.DeploymentProgressStep{{
+							Name: "too deep",
+						}},
+					}},
+				}},
+			},
+			{
+				Name:      "deploy",
+				Status:    godo.DeploymentProgressStepStatus_Error,
+				StartedAt: start.Add(90 * time.Second),
+				Reason:    &godo.DeploymentProgressStepReason{Message: "health check failed"},
+			},
+			{
+				Name:      "finalize",
+				Status:    godo.DeploymentProgressStepStatus_Running,
+				StartedAt: start.Add(time.Minute),
+			},
+		},
+	}
+
+	want := []progressLine{
+		{"/build", "build (1m5s)", godo.DeploymentProgressStepStatus_Success},
+		{"/build/Building service web", "  Building service web", godo.DeploymentProgressStepStatus_Success},
+		{"/build/Building service web/detail", "    detail", ""},
+		{"/deploy", "deploy (30s): health check failed", godo.DeploymentProgressStepStatus_Error},
+		{"/finalize", "finalize (1m0s)", godo.DeploymentProgressStepStatus_Running},
+	}
+
+	got := flattenProgress(progress, now)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestFlattenProgressSummary(t *testing.T) {
+	progress := &godo.DeploymentProgress{
+		SummarySteps: []*godo.DeploymentProgressStep{{Name: "deploy"}},
+	}
+
+	got := flattenProgress(progress, time.Now())
+	if len(got) != 1 || got[0].Message != "deploy" {
+		t.Errorf("unexpected lines: %#v", got)
+	}
+}
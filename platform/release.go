@@ -0,0 +1,340 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+const (
+	// domainPollInterval and domainTimeout pace the wait for domains to be
+	// applied. Issuing certificates can take a while.
+	domainPollInterval = 10 * time.Second
+	domainTimeout      = 15 * time.Minute
+)
+
+// ReleaseConfig holds configuration for a release
+type ReleaseConfig struct {
+	Domains []*DomainConfig `hcl:"domain,block"`
+
+	AuthContext string `hcl:"context,optional"`
+	AccessToken string `hcl:"access_token,optional"`
+}
+
+// DomainConfig describes a custom domain to attach to the app
+type DomainConfig struct {
+	Name     string `hcl:"name,label"`
+	Type     string `hcl:"type,optional"`
+	Wildcard bool   `hcl:"wildcard,optional"`
+	Zone     string `hcl:"zone,optional"`
+}
+
+// ReleaseManager is the ReleaseManager implementation for DigitalOcean
+type ReleaseManager struct {
+	config ReleaseConfig
+	client *godo.Client
+}
+
+// DefaultReleaserFunc implements PlatformReleaser
+func (p *Platform) DefaultReleaserFunc() interface{} {
+	// The default release manager isn't configured, so it takes the
+	// platform's token along with its client.
+	return func() *ReleaseManager {
+		return &ReleaseManager{
+			config: ReleaseConfig{AccessToken: p.config.AccessToken},
+			client: p.client,
+		}
+	}
+}
+
+// Config implements Configurable
+func (r *ReleaseManager) Config() (interface{}, error) {
+	return &r.config, nil
+}
+
+// ConfigSet implement configurableNotify
+func (r *ReleaseManager) ConfigSet(config interface{}) error {
+	c, ok := config.(*ReleaseConfig)
+	if !ok {
+		// The Waypoint SDK should ensure this never gets hit
+		return fmt.Errorf("Expected *ReleaseConfig as parameter")
+	}
+
+	primary := 0
+	for _, d := range c.Domains {
+		switch strings.ToLower(d.Type) {
+		case "":
+			d.Type = string(godo.AppDomainSpecType_Alias)
+		case "primary":
+			d.Type = string(godo.AppDomainSpecType_Primary)
+			primary++
+		case "alias":
+			d.Type = string(godo.AppDomainSpecType_Alias)
+		default:
+			return fmt.Errorf("domain %q has invalid type %q, must be one of: primary, alias", d.Name, d.Type)
+		}
+	}
+
+	if primary > 1 {
+		return fmt.Errorf("only one domain may have type \"primary\"")
+	}
+
+	token, _, err := resolveToken(c.AccessToken, c.AuthContext)
+	if err != nil {
+		return err
+	}
+	c.AccessToken = token
+	r.client = godo.NewFromToken(c.AccessToken)
+
+	return nil
+}
+
+// useStoredToken switches to the access token saved by waypoint auth if no
+// token is configured.
+func (r *ReleaseManager) useStoredToken(dir *datadir.Project) {
+	if r.config.AccessToken != "" {
+		return
+	}
+
+	if token := readToken(dir); token != "" {
+		r.config.AccessToken = token
+		r.client = godo.NewFromToken(token)
+	}
+}
+
+// ReleaseFunc implements ReleaseManager
+func (r *ReleaseManager) ReleaseFunc() interface{} {
+	return r.release
+}
+
+// A ReleaseFunc does not have a strict signature, you can define the parameters
+// you need based on the Available parameters that the Waypoint SDK provides.
+// Waypoint will automatically inject parameters as specified
+// in the signature at run time.
+//
+// Available input parameters:
+// - context.Context
+// - *component.Source
+// - *component.JobInfo
+// - *component.DeploymentConfig
+// - *datadir.Project
+// - *datadir.App
+// - *datadir.Component
+// - hclog.Logger
+// - terminal.UI
+// - *component.LabelSet
+//
+// In addition to default input parameters the Deployment from the DeployFunc step
+// can also be injected.
+//
+// The output parameters for ReleaseFunc must be a Struct which can
+// be serialzied to Protocol Buffers binary format and an error.
+// This Output Value will be made available for other functions
+// as an input parameter.
+//
+// If an error is returned, Waypoint stops the execution flow and
+// returns an error to the user.
+func (r *ReleaseManager) release(ctx context.Context, ui terminal.UI, dir *datadir.Project, deployment *Deployment) (*Release, error) {
+	r.useStoredToken(dir)
+
+	u := ui.Status()
+	defer u.Close()
+	u.Update(fmt.Sprintf("Releasing App Platform app: %s (%s)", deployment.AppName, deployment.AppId))
+
+	release := &Release{
+		AppId:        deployment.AppId,
+		Url:          deployment.LiveUrl,
+		DeploymentId: deployment.ActiveDeploymentId,
+	}
+
+	if len(r.config.Domains) == 0 {
+		u.Step(terminal.StatusOK, fmt.Sprintf("Released %s at %s", deployment.AppName, release.Url))
+		return release, nil
+	}
+
+	app, _, err := r.client.Apps.Get(ctx, deployment.AppId)
+	if err != nil {
+		return nil, fmt.Errorf("Error trying to read app (%s): %s", deployment.AppId, err)
+	}
+
+	// The deployment applying the domains is the first one created after
+	// the app's last one before the update.
+	var since *time.Time
+	if mergeDomains(app.Spec, r.config.Domains) {
+		last := app.LastDeploymentCreatedAt
+		since = &last
+
+		u.Update(fmt.Sprintf("Attaching domains to app %s (%s)", deployment.AppName, deployment.AppId))
+		app, _, err = r.client.Apps.Update(ctx, app.ID, &godo.AppUpdateRequest{Spec: app.Spec})
+		if err != nil {
+			return nil, fmt.Errorf("Error attaching domains to app (%s): %s", deployment.AppId, err)
+		}
+	}
+
+	primary := primaryDomain(r.config.Domains)
+	app, err = r.waitForDomains(ctx, app.ID, since, primary, u)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range r.config.Domains {
+		release.Domains = append(release.Domains, d.Name)
+	}
+	if domain := releaseDomain(r.config.Domains); domain != "" {
+		release.Url = "https://" + domain
+	}
+	if app.ActiveDeployment != nil {
+		release.DeploymentId = app.ActiveDeployment.ID
+	}
+
+	u.Step(terminal.StatusOK, fmt.Sprintf("Released %s at %s", deployment.AppName, release.Url))
+
+	return release, nil
+}
+
+// waitForDomains waits for the deployment triggered by a domain change,
+// the first one created after since, to finish and, when a primary domain is
+// configured, for the app to serve traffic from it. App Platform only
+// switches the live URL over once the domain's certificate has been issued.
+// Without since, the domains weren't changed and only the primary domain is
+// waited for.
+func (r *ReleaseManager) waitForDomains(
+	ctx context.Context,
+	id string,
+	since *time.Time,
+	primary string,
+	u terminal.Status,
+) (*godo.App, error) {
+	w := newPoller(domainPollInterval, domainTimeout)
+
+	var deploymentID string
+	applied := since == nil
+	for {
+		switch {
+		case !applied && deploymentID == "":
+			d, resp, err := deploymentSince(ctx, r.client, id, *since)
+			ok, err := w.done(resp, err)
+			if err != nil {
+				return nil, fmt.Errorf("Error listing app (%s) deployments: %s", id, err)
+			}
+			if ok && d != nil {
+				deploymentID = d.ID
+				continue
+			}
+			u.Update(fmt.Sprintf("Waiting for the deployment applying domains to app (%s) to start", id))
+
+		case !applied:
+			d, resp, err := r.client.Apps.GetDeployment(ctx, id, deploymentID)
+			ok, err := w.done(resp, err)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading app (%s) deployment (%s): %s", id, deploymentID, err)
+			}
+			if ok {
+				switch d.Phase {
+				case godo.DeploymentPhase_Active, godo.DeploymentPhase_Superseded:
+					applied = true
+					continue
+				case godo.DeploymentPhase_Error, godo.DeploymentPhase_Canceled:
+					return nil, fmt.Errorf("Error attaching domains: %s", &deploymentFailedError{
+						appID:        id,
+						deploymentID: d.ID,
+						failures:     failedSteps(d.Progress),
+					})
+				}
+				u.Update(fmt.Sprintf("Waiting for app (%s) deployment (%s) to apply domains. Phase: %s",
+					id, d.ID, d.Phase))
+			}
+
+		default:
+			app, resp, err := r.client.Apps.Get(ctx, id)
+			ok, err := w.done(resp, err)
+			if err != nil {
+				return nil, fmt.Errorf("Error trying to read app (%s) state: %s", id, err)
+			}
+			if ok && (primary == "" || app.LiveDomain == primary) {
+				return app, nil
+			}
+			if primary != "" {
+				u.Update(fmt.Sprintf("Waiting for domain %s to become active", primary))
+			}
+		}
+
+		if err := w.wait(ctx); err == errWaitTimeout {
+			return nil, fmt.Errorf("timeout waiting for app (%s) domains to become active", id)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// mergeDomains adds the configured domains to the spec, updating any that
+// already exist. Domains added outside of Waypoint are left untouched. It
+// reports whether the spec was changed.
+func mergeDomains(spec *godo.AppSpec, domains []*DomainConfig) bool {
+	changed := false
+	for _, d := range domains {
+		want := &godo.AppDomainSpec{
+			Domain:   d.Name,
+			Type:     godo.AppDomainSpecType(d.Type),
+			Wildcard: d.Wildcard,
+			Zone:     d.Zone,
+		}
+
+		found := false
+		for i, existing := range spec.Domains {
+			if existing.Domain != d.Name {
+				continue
+			}
+
+			found = true
+			if *existing != *want {
+				spec.Domains[i] = want
+				changed = true
+			}
+		}
+
+		if !found {
+			spec.Domains = append(spec.Domains, want)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// primaryDomain returns the name of the primary domain, if one is configured.
+func primaryDomain(domains []*DomainConfig) string {
+	for _, d := range domains {
+		if d.Type == string(godo.AppDomainSpecType_Primary) {
+			return d.Name
+		}
+	}
+
+	return ""
+}
+
+// releaseDomain returns the domain the release should be reached at: the
+// primary domain if there is one, otherwise the first non-wildcard domain.
+func releaseDomain(domains []*DomainConfig) string {
+	if primary := primaryDomain(domains); primary != "" {
+		return primary
+	}
+
+	for _, d := range domains {
+		if !d.Wildcard {
+			return d.Name
+		}
+	}
+
+	return ""
+}
+
+// URL implements component.Release
+func (r *Release) URL() string {
+	return r.Url
+}
@@ -0,0 +1,109 @@
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+)
+
+func TestMergeDomains(t *testing.T) {
+	spec := &godo.AppSpec{
+		Domains: []*godo.AppDomainSpec{
+			{Domain: "console.example.com", Type: godo.AppDomainSpecType_Alias},
+			{Domain: "example.com", Type: godo.AppDomainSpecType_Alias},
+		},
+	}
+
+	domains := []*DomainConfig{
+		{Name: "example.com", Type: string(godo.AppDomainSpecType_Primary)},
+		{Name: "example.org", Type: string(godo.AppDomainSpecType_Alias), Wildcard: true, Zone: "example.org"},
+	}
+
+	if !mergeDomains(spec, domains) {
+		t.Fatalf("expected spec to change")
+	}
+
+	if len(spec.Domains) != 3 {
+		t.Fatalf("got %d domains, want 3", len(spec.Domains))
+	}
+	if spec.Domains[0].Domain != "console.example.com" {
+		t.Errorf("domain added outside of Waypoint was not preserved")
+	}
+	if spec.Domains[1].Type != godo.AppDomainSpecType_Primary {
+		t.Errorf("got type %q, want PRIMARY", spec.Domains[1].Type)
+	}
+	if !spec.Domains[2].Wildcard || spec.Domains[2].Zone != "example.org" {
+		t.Errorf("got %s, want wildcard domain in zone example.org", godo.Stringify(spec.Domains[2]))
+	}
+
+	if mergeDomains(spec, domains) {
+		t.Errorf("expected merging the same domains again to be a no-op")
+	}
+}
+
+func TestReleaseDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []*DomainConfig
+		want    string
+	}{
+		{"none", nil, ""},
+		{
+			"primary",
+			[]*DomainConfig{
+				{Name: "www.example.com", Type: string(godo.AppDomainSpecType_Alias)},
+				{Name: "example.com", Type: string(godo.AppDomainSpecType_Primary)},
+			},
+			"example.com",
+		},
+		{
+			"first non-wildcard alias",
+			[]*DomainConfig{
+				{Name: "example.com", Type: string(godo.AppDomainSpecType_Alias), Wildcard: true},
+				{Name: "www.example.com", Type: string(godo.AppDomainSpecType_Alias)},
+			},
+			"www.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releaseDomain(tt.domains); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultReleaserToken(t *testing.T) {
+	path, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	dir := &datadir.Project{Dir: datadir.NewBasicDir(filepath.Join(path, "cache"), filepath.Join(path, "data"))}
+	if err := writeToken(dir, "saved"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := godo.NewFromToken("configured")
+	p := &Platform{config: DeployConfig{AccessToken: "configured"}, client: client}
+
+	r := p.DefaultReleaserFunc().(func() *ReleaseManager)()
+	r.useStoredToken(dir)
+	if r.client != client || r.config.AccessToken != "configured" {
+		t.Errorf("the saved token replaced the platform's token")
+	}
+
+	p = &Platform{client: godo.NewFromToken("")}
+	r = p.DefaultReleaserFunc().(func() *ReleaseManager)()
+	r.useStoredToken(dir)
+	if r.config.AccessToken != "saved" {
+		t.Errorf("got token %q without a configured token, want the saved one", r.config.AccessToken)
+	}
+}
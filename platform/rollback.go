@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+// rollback re-submits the spec of the deployment that was active before
+// failed, waits for it to become active and returns deployErr annotated with
+// the outcome.
+func (p *Platform) rollback(ctx context.Context, ui terminal.UI, failed *deploymentFailedError, deployErr error) error {
+	previous, err := p.previousDeployment(ctx, failed.appID, failed.deploymentID)
+	if err != nil {
+		return rollbackError(deployErr, "", "", err)
+	}
+	if previous == nil || previous.Spec == nil {
+		return rollbackError(deployErr, "", "", nil)
+	}
+
+	ui.Output("Rolling back app (%s) to deployment (%s)", failed.appID, previous.ID, terminal.WithWarningStyle())
+
+	_, _, err = p.client.Apps.Update(ctx, failed.appID, &godo.AppUpdateRequest{Spec: previous.Spec})
+	if err != nil {
+		return rollbackError(deployErr, previous.ID, "", err)
+	}
+
+	app, err := p.waitForAppDeployment(ctx, ui, failed.appID)
+	if err != nil {
+		return rollbackError(deployErr, previous.ID, "", err)
+	}
+
+	return rollbackError(deployErr, previous.ID, app.ActiveDeployment.ID, nil)
+}
+
+// rollbackError annotates deployErr with the outcome of rolling back to the
+// deployment previousID in the deployment rollbackID. An empty previousID
+// means there was nothing to roll back to, or, with err, that it couldn't be
+// found.
+func rollbackError(deployErr error, previousID, rollbackID string, err error) error {
+	switch {
+	case previousID == "" && err != nil:
+		return fmt.Errorf("%s; unable to roll back: %s", deployErr, err)
+	case previousID == "":
+		return fmt.Errorf("%s; no previously active deployment to roll back to", deployErr)
+	case err != nil:
+		return fmt.Errorf("%s; rollback to deployment (%s) failed: %s", deployErr, previousID, err)
+	}
+
+	return fmt.Errorf("%s; rolled back to the spec of deployment (%s) in deployment (%s)",
+		deployErr, previousID, rollbackID)
+}
+
+// previousDeployment returns the app's active deployment other than
+// failedID, with its spec, or nil if there isn't one.
+func (p *Platform) previousDeployment(ctx context.Context, appID, failedID string) (*godo.Deployment, error) {
+	opt := &godo.ListOptions{PerPage: 20, Page: 1}
+	for {
+		deployments, resp, err := p.client.Apps.ListDeployments(ctx, appID, opt)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing app (%s) deployments: %s", appID, err)
+		}
+
+		if d := activeDeployment(deployments, failedID); d != nil {
+			deployment, _, err := p.client.Apps.GetDeployment(ctx, appID, d.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading app (%s) deployment (%s): %s", appID, d.ID, err)
+			}
+
+			return deployment, nil
+		}
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			return nil, nil
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+
+		opt.Page = page + 1
+	}
+}
+
+// activeDeployment returns the first active deployment other than failedID,
+// or nil if there isn't one.
+func activeDeployment(deployments []*godo.Deployment, failedID string) *godo.Deployment {
+	for _, d := range deployments {
+		if d.ID != failedID && d.Phase == godo.DeploymentPhase_Active {
+			return d
+		}
+	}
+
+	return nil
+}
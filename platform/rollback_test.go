@@ -0,0 +1,86 @@
+package platform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestActiveDeployment(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []*godo.Deployment
+		want        string
+	}{
+		{
+			name: "previous active deployment",
+			deployments: []*godo.Deployment{
+				{ID: "failed", Phase: godo.DeploymentPhase_Error},
+				{ID: "superseded", Phase: godo.DeploymentPhase_Superseded},
+				{ID: "previous", Phase: godo.DeploymentPhase_Active},
+				{ID: "older", Phase: godo.DeploymentPhase_Active},
+			},
+			want: "previous",
+		},
+		{
+			name: "skips the failed deployment",
+			deployments: []*godo.Deployment{
+				{ID: "failed", Phase: godo.DeploymentPhase_Active},
+				{ID: "previous", Phase: godo.DeploymentPhase_Active},
+			},
+			want: "previous",
+		},
+		{
+			name: "no previous deployment",
+			deployments: []*godo.Deployment{
+				{ID: "failed", Phase: godo.DeploymentPhase_Error},
+				{ID: "canceled", Phase: godo.DeploymentPhase_Canceled},
+			},
+		},
+		{
+			name: "no deployments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := activeDeployment(tt.deployments, "failed")
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("got %s, want none", got.ID)
+			case tt.want != "" && (got == nil || got.ID != tt.want):
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollbackError(t *testing.T) {
+	deployErr := errors.New("error deploying app (app-id)")
+
+	tests := []struct {
+		name       string
+		previousID string
+		rollbackID string
+		err        error
+		want       string
+	}{
+		{"unable to find previous", "", "", errors.New("forbidden"),
+			"error deploying app (app-id); unable to roll back: forbidden"},
+		{"no previous", "", "", nil,
+			"error deploying app (app-id); no previously active deployment to roll back to"},
+		{"rollback failed", "previous", "", errors.New("timed out"),
+			"error deploying app (app-id); rollback to deployment (previous) failed: timed out"},
+		{"rolled back", "previous", "rollback", nil,
+			"error deploying app (app-id); rolled back to the spec of deployment (previous) in deployment (rollback)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rollbackError(deployErr, tt.previousID, tt.rollbackID, tt.err).Error(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint/builtin/docker"
+)
+
+// ServiceConfig holds configuration for a service component. Services
+// receive HTTP traffic.
+type ServiceConfig struct {
+	Name             string `hcl:"name,label"`
+	InstanceSizeSlug string `hcl:"instance_size_slug,optional"`
+	InstanceCount    int64  `hcl:"instance_count,optional"`
+	RunCommand       string `hcl:"run_command,optional"`
+
+	HTTPPort      int64    `hcl:"http_port,optional"`
+	Path          string   `hcl:"path,optional"`
+	Routes        []string `hcl:"routes,optional"`
+	InternalPorts []int64  `hcl:"internal_ports,optional"`
+
+	HealthCheck *HealthCheckConfig `hcl:"health_check,block"`
+	CORS        *CORSConfig        `hcl:"cors,block"`
+
+	Env       []*EnvConfig `hcl:"env,block"`
+	SecretEnv []*EnvConfig `hcl:"secret_env,block"`
+}
+
+// HealthCheckConfig holds configuration for a service's health check. If
+// no path is set, a TCP health check is used.
+type HealthCheckConfig struct {
+	Path                string `hcl:"path,optional"`
+	InitialDelaySeconds int32  `hcl:"initial_delay_seconds,optional"`
+	PeriodSeconds       int32  `hcl:"period_seconds,optional"`
+	TimeoutSeconds      int32  `hcl:"timeout_seconds,optional"`
+	SuccessThreshold    int32  `hcl:"success_threshold,optional"`
+	FailureThreshold    int32  `hcl:"failure_threshold,optional"`
+}
+
+// CORSConfig holds the CORS policy for a service
+type CORSConfig struct {
+	AllowOrigins []*OriginConfig `hcl:"allow_origin,block"`
+}
+
+// OriginConfig matches allowed CORS origins. Exactly one of Exact, Prefix
+// or Regex must be set.
+type OriginConfig struct {
+	Exact  string `hcl:"exact,optional"`
+	Prefix string `hcl:"prefix,optional"`
+	Regex  string `hcl:"regex,optional"`
+}
+
+// WorkerConfig holds configuration for a worker component. Workers run
+// continuously but do not receive HTTP traffic.
+type WorkerConfig struct {
+	Name             string `hcl:"name,label"`
+	InstanceSizeSlug string `hcl:"instance_size_slug,optional"`
+	InstanceCount    int64  `hcl:"instance_count,optional"`
+	RunCommand       string `hcl:"run_command,optional"`
+
+	Env       []*EnvConfig `hcl:"env,block"`
+	SecretEnv []*EnvConfig `hcl:"secret_env,block"`
+}
+
+// JobConfig holds configuration for a job component. Jobs run to
+// completion before or after a deployment.
+type JobConfig struct {
+	Name             string `hcl:"name,label"`
+	Kind             string `hcl:"kind,optional"`
+	InstanceSizeSlug string `hcl:"instance_size_slug,optional"`
+	InstanceCount    int64  `hcl:"instance_count,optional"`
+	RunCommand       string `hcl:"run_command,optional"`
+
+	Env       []*EnvConfig `hcl:"env,block"`
+	SecretEnv []*EnvConfig `hcl:"secret_env,block"`
+}
+
+// DatabaseConfig holds configuration for a database attached to the app.
+// Databases are either dev databases managed by App Platform or existing
+// managed database clusters.
+type DatabaseConfig struct {
+	Name       string `hcl:"name,label"`
+	Engine     string `hcl:"engine,optional"`
+	Version    string `hcl:"version,optional"`
+	Production bool   `hcl:"production,optional"`
+
+	ClusterName string `hcl:"cluster_name,optional"`
+	DBName      string `hcl:"db_name,optional"`
+	DBUser      string `hcl:"db_user,optional"`
+}
+
+// validateComponents checks the service, worker and job blocks, normalizing
+// job kinds and applying defaults from the top level configuration.
+func validateComponents(c *DeployConfig) error {
+	// Only http_port and path are inherited by service blocks, so the
+	// rest of the top level service configuration would be ignored.
+	if len(c.Services) > 0 && (len(c.Routes) > 0 || len(c.InternalPorts) > 0 || c.HealthCheck != nil || c.CORS != nil) {
+		return fmt.Errorf("routes, internal_ports, health_check and cors must be set in the service blocks " +
+			"when service blocks are configured")
+	}
+
+	names := map[string]bool{}
+	unique := func(name string) error {
+		if names[name] {
+			return fmt.Errorf("component name %q is used more than once", name)
+		}
+		names[name] = true
+		return nil
+	}
+
+	for _, s := range c.Services {
+		if err := unique(s.Name); err != nil {
+			return err
+		}
+		if err := validateEnvs(s.Env, s.SecretEnv); err != nil {
+			return fmt.Errorf("service %q: %s", s.Name, err)
+		}
+
+		if s.HTTPPort == 0 {
+			s.HTTPPort = c.HTTPPort
+		}
+		if s.Path == "" {
+			s.Path = c.Path
+		}
+
+		if err := validateService(s); err != nil {
+			return fmt.Errorf("service %q: %s", s.Name, err)
+		}
+	}
+
+	for _, w := range c.Workers {
+		if err := unique(w.Name); err != nil {
+			return err
+		}
+		if err := validateEnvs(w.Env, w.SecretEnv); err != nil {
+			return fmt.Errorf("worker %q: %s", w.Name, err)
+		}
+	}
+
+	for _, j := range c.Jobs {
+		if err := unique(j.Name); err != nil {
+			return err
+		}
+		if err := validateEnvs(j.Env, j.SecretEnv); err != nil {
+			return fmt.Errorf("job %q: %s", j.Name, err)
+		}
+
+		switch kind := godo.AppJobSpecKind(strings.ToUpper(j.Kind)); kind {
+		case "":
+		case godo.AppJobSpecKind_PreDeploy, godo.AppJobSpecKind_PostDeploy, godo.AppJobSpecKind_FailedDeploy:
+			j.Kind = string(kind)
+		default:
+			return fmt.Errorf("job %q has invalid kind %q, must be one of: %s, %s, %s",
+				j.Name, j.Kind, godo.AppJobSpecKind_PreDeploy, godo.AppJobSpecKind_PostDeploy,
+				godo.AppJobSpecKind_FailedDeploy)
+		}
+	}
+
+	for _, d := range c.Databases {
+		if err := unique(d.Name); err != nil {
+			return err
+		}
+		if err := validateDatabase(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// duplicateName returns a name shared by more than one of spec's
+// components, or an empty string if each name is unique.
+func duplicateName(spec *godo.AppSpec) string {
+	names := map[string]bool{}
+	all := append(specComponents(spec), specDatabases(spec)...)
+	all = append(all, specStaticSites(spec)...)
+
+	for _, name := range all {
+		if names[name] {
+			return name
+		}
+		names[name] = true
+	}
+
+	return ""
+}
+
+// validateService checks the routing and health check configuration of a
+// service.
+func validateService(s *ServiceConfig) error {
+	for _, r := range s.Routes {
+		if !strings.HasPrefix(r, "/") {
+			return fmt.Errorf("route %q must start with \"/\"", r)
+		}
+	}
+
+	for _, port := range s.InternalPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("internal port %d is out of range", port)
+		}
+		if port == s.HTTPPort {
+			return fmt.Errorf("internal port %d is also the http_port", port)
+		}
+	}
+
+	if s.HealthCheck != nil {
+		h := s.HealthCheck
+		for _, v := range []int32{h.InitialDelaySeconds, h.PeriodSeconds, h.TimeoutSeconds, h.SuccessThreshold, h.FailureThreshold} {
+			if v < 0 {
+				return fmt.Errorf("health_check values must not be negative")
+			}
+		}
+	}
+
+	if s.CORS != nil {
+		for _, o := range s.CORS.AllowOrigins {
+			set := 0
+			for _, v := range []string{o.Exact, o.Prefix, o.Regex} {
+				if v != "" {
+					set++
+				}
+			}
+			if set != 1 {
+				return fmt.Errorf("each cors allow_origin block must set exactly one of exact, prefix or regex")
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDatabase normalizes the engine of a database and checks that it
+// is either a dev database or refers to a managed cluster.
+func validateDatabase(d *DatabaseConfig) error {
+	switch engine := strings.ToUpper(d.Engine); engine {
+	case "":
+		d.Engine = string(godo.AppDatabaseSpecEngine_PG)
+	case "POSTGRES", "POSTGRESQL":
+		d.Engine = string(godo.AppDatabaseSpecEngine_PG)
+	case string(godo.AppDatabaseSpecEngine_PG), string(godo.AppDatabaseSpecEngine_MySQL), string(godo.AppDatabaseSpecEngine_Redis):
+		d.Engine = engine
+	default:
+		return fmt.Errorf("database %q has invalid engine %q, must be one of: %s, %s, %s",
+			d.Name, d.Engine, godo.AppDatabaseSpecEngine_PG, godo.AppDatabaseSpecEngine_MySQL,
+			godo.AppDatabaseSpecEngine_Redis)
+	}
+
+	if d.ClusterName == "" {
+		if d.Production {
+			return fmt.Errorf("database %q is a production database and requires cluster_name", d.Name)
+		}
+		if d.DBName != "" || d.DBUser != "" {
+			return fmt.Errorf("database %q sets db_name or db_user, which require cluster_name", d.Name)
+		}
+		if d.Engine != string(godo.AppDatabaseSpecEngine_PG) {
+			return fmt.Errorf("database %q: dev databases only support the %s engine", d.Name, godo.AppDatabaseSpecEngine_PG)
+		}
+	} else {
+		// Databases in managed clusters are always production databases.
+		d.Production = true
+	}
+
+	return nil
+}
+
+// buildSpec builds the app spec for deploying img. If base, loaded from the
+// configured spec file, is given, img is deployed to its components.
+// Otherwise the spec is built from the configuration and every component
+// runs img.
+func (p *Platform) buildSpec(
+	name string,
+	img *docker.Image,
+	deployConfig *component.DeploymentConfig,
+	existing *godo.App,
+	base *godo.AppSpec,
+) (*godo.AppSpec, error) {
+	registry, repository, regType := parseImage(img)
+	image := func() *godo.ImageSourceSpec {
+		return &godo.ImageSourceSpec{
+			RegistryType: regType,
+			Registry:     registry,
+			Repository:   repository,
+			Tag:          img.Tag,
+		}
+	}
+
+	spec := base
+	if spec != nil {
+		spec.Name = name
+		if err := p.overlaySpecFile(spec, image, deployConfig); err != nil {
+			return nil, err
+		}
+	} else {
+		spec = p.configSpec(name, image, deployConfig)
+
+		// The names of the blocks are checked by ConfigSet, but the
+		// service created without service blocks is only named now.
+		if dup := duplicateName(spec); dup != "" {
+			return nil, fmt.Errorf("component name %q is used more than once; "+
+				"without service blocks, the service is named after the app", dup)
+		}
+	}
+
+	var err error
+	for _, s := range spec.Services {
+		if s.Envs, err = mergeEnvs(existingEnvs(existing, s.Name), s.Envs); err != nil {
+			return nil, fmt.Errorf("service %q: %s", s.Name, err)
+		}
+	}
+	for _, w := range spec.Workers {
+		if w.Envs, err = mergeEnvs(existingEnvs(existing, w.Name), w.Envs); err != nil {
+			return nil, fmt.Errorf("worker %q: %s", w.Name, err)
+		}
+	}
+	for _, j := range spec.Jobs {
+		if j.Envs, err = mergeEnvs(existingEnvs(existing, j.Name), j.Envs); err != nil {
+			return nil, fmt.Errorf("job %q: %s", j.Name, err)
+		}
+	}
+
+	return spec, nil
+}
+
+// configSpec builds an app spec from the configuration in which every
+// component runs the image. If no service blocks are configured, a single
+// service named after the app is created from the top level configuration.
+func (p *Platform) configSpec(
+	name string,
+	image func() *godo.ImageSourceSpec,
+	deployConfig *component.DeploymentConfig,
+) *godo.AppSpec {
+	c := p.config
+	services := c.Services
+	if len(services) == 0 {
+		services = []*ServiceConfig{{
+			Name:          name,
+			HTTPPort:      c.HTTPPort,
+			Path:          c.Path,
+			Routes:        c.Routes,
+			InternalPorts: c.InternalPorts,
+			HealthCheck:   c.HealthCheck,
+			CORS:          c.CORS,
+		}}
+	}
+
+	spec := &godo.AppSpec{Name: name}
+	for _, s := range services {
+		spec.Services = append(spec.Services, &godo.AppServiceSpec{
+			Name:             s.Name,
+			InstanceSizeSlug: stringOr(s.InstanceSizeSlug, c.InstanceSizeSlug),
+			InstanceCount:    intOr(s.InstanceCount, c.InstanceCount),
+			Image:            image(),
+			RunCommand:       s.RunCommand,
+			Envs:             p.componentEnvs(s.Env, s.SecretEnv, deployConfig),
+			HTTPPort:         s.HTTPPort,
+			Routes:           serviceRoutes(s),
+			InternalPorts:    s.InternalPorts,
+			HealthCheck:      serviceHealthCheck(s.HealthCheck),
+			CORS:             serviceCORS(s.CORS),
+		})
+	}
+
+	for _, w := range c.Workers {
+		spec.Workers = append(spec.Workers, &godo.AppWorkerSpec{
+			Name:             w.Name,
+			InstanceSizeSlug: stringOr(w.InstanceSizeSlug, c.InstanceSizeSlug),
+			InstanceCount:    intOr(w.InstanceCount, c.InstanceCount),
+			Image:            image(),
+			RunCommand:       w.RunCommand,
+			Envs:             p.componentEnvs(w.Env, w.SecretEnv, deployConfig),
+		})
+	}
+
+	for _, j := range c.Jobs {
+		spec.Jobs = append(spec.Jobs, &godo.AppJobSpec{
+			Name:             j.Name,
+			Kind:             godo.AppJobSpecKind(j.Kind),
+			InstanceSizeSlug: stringOr(j.InstanceSizeSlug, c.InstanceSizeSlug),
+			InstanceCount:    intOr(j.InstanceCount, c.InstanceCount),
+			Image:            image(),
+			RunCommand:       j.RunCommand,
+			Envs:             p.componentEnvs(j.Env, j.SecretEnv, nil),
+		})
+	}
+
+	for _, d := range c.Databases {
+		spec.Databases = append(spec.Databases, &godo.AppDatabaseSpec{
+			Name:        d.Name,
+			Engine:      godo.AppDatabaseSpecEngine(d.Engine),
+			Version:     d.Version,
+			Production:  d.Production,
+			ClusterName: d.ClusterName,
+			DBName:      d.DBName,
+			DBUser:      d.DBUser,
+		})
+	}
+
+	return spec
+}
+
+// overlaySpecFile deploys the image to the components of a spec loaded from
+// the spec file, adding the top level variables and the Waypoint entrypoint
+// configuration to the environment of each component the image is deployed
+// to.
+func (p *Platform) overlaySpecFile(
+	spec *godo.AppSpec,
+	image func() *godo.ImageSourceSpec,
+	deployConfig *component.DeploymentConfig,
+) error {
+	overlaid, err := overlayImage(spec, image, p.config.SpecComponents)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range spec.Services {
+		if overlaid[s.Name] {
+			s.Envs = overlayEnvs(s.Envs, p.componentEnvs(nil, nil, deployConfig))
+		}
+	}
+	for _, w := range spec.Workers {
+		if overlaid[w.Name] {
+			w.Envs = overlayEnvs(w.Envs, p.componentEnvs(nil, nil, deployConfig))
+		}
+	}
+	for _, j := range spec.Jobs {
+		if overlaid[j.Name] {
+			j.Envs = overlayEnvs(j.Envs, p.componentEnvs(nil, nil, nil))
+		}
+	}
+
+	return nil
+}
+
+// serviceRoutes returns the routes for a service. The routes attribute
+// takes precedence over the single path.
+func serviceRoutes(s *ServiceConfig) []*godo.AppRouteSpec {
+	paths := s.Routes
+	if len(paths) == 0 {
+		paths = []string{s.Path}
+	}
+
+	var routes []*godo.AppRouteSpec
+	for _, path := range paths {
+		routes = append(routes, &godo.AppRouteSpec{Path: path})
+	}
+
+	return routes
+}
+
+func serviceHealthCheck(h *HealthCheckConfig) *godo.AppServiceSpecHealthCheck {
+	if h == nil {
+		return nil
+	}
+
+	return &godo.AppServiceSpecHealthCheck{
+		HTTPPath:            h.Path,
+		InitialDelaySeconds: h.InitialDelaySeconds,
+		PeriodSeconds:       h.PeriodSeconds,
+		TimeoutSeconds:      h.TimeoutSeconds,
+		SuccessThreshold:    h.SuccessThreshold,
+		FailureThreshold:    h.FailureThreshold,
+	}
+}
+
+func serviceCORS(c *CORSConfig) *godo.AppCORSPolicy {
+	if c == nil {
+		return nil
+	}
+
+	policy := &godo.AppCORSPolicy{}
+	for _, o := range c.AllowOrigins {
+		policy.AllowOrigins = append(policy.AllowOrigins, &godo.AppStringMatch{
+			Exact:  o.Exact,
+			Prefix: o.Prefix,
+			Regex:  o.Regex,
+		})
+	}
+
+	return policy
+}
+
+// componentEnvs returns the variables for a component: the top level env and
+// secret_env blocks, overridden by the component's own, followed by the
+// entrypoint configuration when deployConfig is set.
+func (p *Platform) componentEnvs(env, secrets []*EnvConfig, deployConfig *component.DeploymentConfig) []*godo.AppVariableDefinition {
+	defs := overlayEnvs(buildEnvs(p.config.Env, p.config.SecretEnv), buildEnvs(env, secrets))
+	if deployConfig != nil && !p.config.DisableEntrypoint {
+		defs = appendEntrypointEnvs(defs, deployConfig)
+	}
+
+	return defs
+}
+
+// specComponents returns the names of the components in spec that run the
+// deployed image. Databases are returned separately by specDatabases.
+func specComponents(spec *godo.AppSpec) []string {
+	var names []string
+	for _, s := range spec.Services {
+		names = append(names, s.Name)
+	}
+	for _, w := range spec.Workers {
+		names = append(names, w.Name)
+	}
+	for _, j := range spec.Jobs {
+		names = append(names, j.Name)
+	}
+
+	return names
+}
+
+// specDatabases returns the names of the databases in spec.
+func specDatabases(spec *godo.AppSpec) []string {
+	var names []string
+	for _, d := range spec.Databases {
+		names = append(names, d.Name)
+	}
+
+	return names
+}
+
+// specStaticSites returns the names of the static sites in spec.
+func specStaticSites(spec *godo.AppSpec) []string {
+	var names []string
+	for _, s := range spec.StaticSites {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+// existingEnvs returns the variables of the named component in an existing
+// app, if present.
+func existingEnvs(app *godo.App, name string) []*godo.AppVariableDefinition {
+	if app == nil || app.Spec == nil {
+		return nil
+	}
+
+	for _, s := range app.Spec.Services {
+		if s.Name == name {
+			return s.Envs
+		}
+	}
+	for _, w := range app.Spec.Workers {
+		if w.Name == name {
+			return w.Envs
+		}
+	}
+	for _, j := range app.Spec.Jobs {
+		if j.Name == name {
+			return j.Envs
+		}
+	}
+
+	return nil
+}
+
+func stringOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func intOr(v, def int64) int64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
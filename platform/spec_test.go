@@ -0,0 +1,248 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint/builtin/docker"
+)
+
+func TestBuildSpec(t *testing.T) {
+	img := &docker.Image{Image: "registry.digitalocean.com/foo/bar", Tag: "v1"}
+
+	t.Run("default service", func(t *testing.T) {
+		p := &Platform{config: DeployConfig{
+			InstanceSizeSlug:  "basic-xs",
+			InstanceCount:     2,
+			HTTPPort:          8080,
+			Path:              "/",
+			DisableEntrypoint: true,
+		}}
+
+		spec, err := p.buildSpec("bar", img, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(spec.Services) != 1 {
+			t.Fatalf("got %d services, want 1", len(spec.Services))
+		}
+		svc := spec.Services[0]
+		if svc.Name != "bar" || svc.InstanceSizeSlug != "basic-xs" || svc.InstanceCount != 2 {
+			t.Errorf("unexpected service: %s", godo.Stringify(svc))
+		}
+		if svc.Image.Repository != "bar" || svc.Image.Tag != "v1" {
+			t.Errorf("unexpected image: %s", godo.Stringify(svc.Image))
+		}
+	})
+
+	t.Run("components", func(t *testing.T) {
+		c := DeployConfig{
+			InstanceSizeSlug:  "basic-xxs",
+			HTTPPort:          8080,
+			Path:              "/",
+			DisableEntrypoint: true,
+			Env:               []*EnvConfig{{Key: "LOG_LEVEL", Value: "info"}},
+			Services:          []*ServiceConfig{{Name: "web"}},
+			Workers: []*WorkerConfig{{
+				Name:             "queue",
+				InstanceSizeSlug: "professional-xs",
+				RunCommand:       "bin/worker",
+				Env:              []*EnvConfig{{Key: "LOG_LEVEL", Value: "debug"}},
+			}},
+			Jobs: []*JobConfig{{Name: "migrate", Kind: "pre_deploy", RunCommand: "bin/migrate"}},
+		}
+		if err := validateComponents(&c); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		p := &Platform{config: c}
+		spec, err := p.buildSpec("bar", img, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(spec.Services) != 1 || spec.Services[0].HTTPPort != 8080 || spec.Services[0].Routes[0].Path != "/" {
+			t.Errorf("unexpected services: %s", godo.Stringify(spec.Services))
+		}
+		if spec.Services[0].Envs[0].Value != "info" {
+			t.Errorf("got LOG_LEVEL %q for service, want info", spec.Services[0].Envs[0].Value)
+		}
+
+		w := spec.Workers[0]
+		if w.InstanceSizeSlug != "professional-xs" || w.RunCommand != "bin/worker" || w.Image.Tag != "v1" {
+			t.Errorf("unexpected worker: %s", godo.Stringify(w))
+		}
+		if len(w.Envs) != 1 || w.Envs[0].Value != "debug" {
+			t.Errorf("got envs %s for worker, want LOG_LEVEL=debug", godo.Stringify(w.Envs))
+		}
+
+		j := spec.Jobs[0]
+		if j.Kind != godo.AppJobSpecKind_PreDeploy || j.InstanceSizeSlug != "basic-xxs" {
+			t.Errorf("unexpected job: %s", godo.Stringify(j))
+		}
+
+		want := []string{"web", "queue", "migrate"}
+		got := specComponents(spec)
+		if len(got) != len(want) {
+			t.Fatalf("got components %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("got components %v, want %v", got, want)
+			}
+		}
+	})
+}
+
+func TestValidateComponents(t *testing.T) {
+	c := &DeployConfig{
+		Services: []*ServiceConfig{{Name: "web"}},
+		Workers:  []*WorkerConfig{{Name: "web"}},
+	}
+	if err := validateComponents(c); err == nil {
+		t.Errorf("expected an error for a duplicate component name")
+	}
+
+	c = &DeployConfig{Jobs: []*JobConfig{{Name: "migrate", Kind: "sometimes"}}}
+	if err := validateComponents(c); err == nil {
+		t.Errorf("expected an error for an invalid job kind")
+	}
+
+	c = &DeployConfig{
+		Routes:   []string{"/api"},
+		Services: []*ServiceConfig{{Name: "web"}},
+	}
+	if err := validateComponents(c); err == nil {
+		t.Errorf("expected an error for top level routes alongside service blocks")
+	}
+
+	c = &DeployConfig{
+		HTTPPort: 3000,
+		Path:     "/app",
+		Services: []*ServiceConfig{{Name: "web"}},
+	}
+	if err := validateComponents(c); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if s := c.Services[0]; s.HTTPPort != 3000 || s.Path != "/app" {
+		t.Errorf("http_port and path not inherited: %+v", s)
+	}
+}
+
+func TestBuildSpecImplicitServiceName(t *testing.T) {
+	img := &docker.Image{Image: "registry.digitalocean.com/foo/bar", Tag: "v1"}
+	p := &Platform{config: DeployConfig{
+		HTTPPort:  8080,
+		Path:      "/",
+		Workers:   []*WorkerConfig{{Name: "bar"}},
+		Databases: []*DatabaseConfig{{Name: "db"}},
+	}}
+
+	_, err := p.buildSpec("bar", img, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), `"bar" is used more than once`) {
+		t.Errorf("got %v, want an error for the worker named after the app", err)
+	}
+
+	if _, err := p.buildSpec("web", img, nil, nil, nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	tests := []struct {
+		name       string
+		db         *DatabaseConfig
+		engine     string
+		production bool
+		err        bool
+	}{
+		{"dev", &DatabaseConfig{Name: "db"}, "PG", false, false},
+		{"dev postgres", &DatabaseConfig{Name: "db", Engine: "postgres"}, "PG", false, false},
+		{"dev mysql", &DatabaseConfig{Name: "db", Engine: "mysql"}, "", false, true},
+		{"cluster", &DatabaseConfig{Name: "db", Engine: "mysql", ClusterName: "prod", DBName: "app"}, "MYSQL", true, false},
+		{"production without cluster", &DatabaseConfig{Name: "db", Production: true}, "", false, true},
+		{"db_user without cluster", &DatabaseConfig{Name: "db", DBUser: "app"}, "", false, true},
+		{"invalid engine", &DatabaseConfig{Name: "db", Engine: "mongo", ClusterName: "prod"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDatabase(tt.db)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.db.Engine != tt.engine {
+				t.Errorf("got engine %q, want %q", tt.db.Engine, tt.engine)
+			}
+			if tt.db.Production != tt.production {
+				t.Errorf("got production %t, want %t", tt.db.Production, tt.production)
+			}
+		})
+	}
+}
+
+func TestBuildSpecServiceRouting(t *testing.T) {
+	img := &docker.Image{Image: "foo/bar", Tag: "latest"}
+	p := &Platform{config: DeployConfig{
+		HTTPPort:          8080,
+		Path:              "/",
+		Routes:            []string{"/api", "/v1"},
+		InternalPorts:     []int64{9090},
+		DisableEntrypoint: true,
+		HealthCheck:       &HealthCheckConfig{Path: "/healthz", FailureThreshold: 3},
+		CORS: &CORSConfig{AllowOrigins: []*OriginConfig{
+			{Exact: "https://example.com"},
+			{Regex: `^https://.*\.example\.com$`},
+		}},
+	}}
+
+	spec, err := p.buildSpec("bar", img, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	svc := spec.Services[0]
+	if len(svc.Routes) != 2 || svc.Routes[0].Path != "/api" || svc.Routes[1].Path != "/v1" {
+		t.Errorf("unexpected routes: %s", godo.Stringify(svc.Routes))
+	}
+	if len(svc.InternalPorts) != 1 || svc.InternalPorts[0] != 9090 {
+		t.Errorf("unexpected internal ports: %v", svc.InternalPorts)
+	}
+	if svc.HealthCheck.HTTPPath != "/healthz" || svc.HealthCheck.FailureThreshold != 3 {
+		t.Errorf("unexpected health check: %s", godo.Stringify(svc.HealthCheck))
+	}
+	if len(svc.CORS.AllowOrigins) != 2 || svc.CORS.AllowOrigins[1].Regex == "" {
+		t.Errorf("unexpected CORS policy: %s", godo.Stringify(svc.CORS))
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  *ServiceConfig
+	}{
+		{"relative route", &ServiceConfig{Routes: []string{"api"}}},
+		{"internal port out of range", &ServiceConfig{InternalPorts: []int64{70000}}},
+		{"internal port is http port", &ServiceConfig{HTTPPort: 8080, InternalPorts: []int64{8080}}},
+		{"negative health check", &ServiceConfig{HealthCheck: &HealthCheckConfig{PeriodSeconds: -1}}},
+		{"empty origin", &ServiceConfig{CORS: &CORSConfig{AllowOrigins: []*OriginConfig{{}}}}},
+		{"ambiguous origin", &ServiceConfig{CORS: &CORSConfig{AllowOrigins: []*OriginConfig{{Exact: "a", Prefix: "b"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateService(tt.svc); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"gopkg.in/yaml.v3"
+)
+
+// specVarPattern matches an HCL-style interpolation such as ${var.name}. A
+// leading $$ escapes it, leaving a literal ${...} in the spec.
+var specVarPattern = regexp.MustCompile(`\$?\$\{\s*([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_-]*)\s*\}`)
+
+// specFileError is an error in a spec file, reported with the line it is on.
+type specFileError struct {
+	path string
+	line int
+	msg  string
+}
+
+func (e *specFileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.path, e.line, e.msg)
+}
+
+// loadSpecFile reads an App Platform app spec from a YAML or JSON file,
+// replacing any ${var.*}, ${app.*} or ${image.*} references with the given
+// variables. Other references, such as the ${db.DATABASE_URL} bindable
+// variables App Platform provides, are left for App Platform to resolve.
+// That includes references to a component named after one of the variables'
+// namespaces, such as ${app.PUBLIC_URL} for a service named app, as long as
+// the variable isn't defined.
+//
+// The file is checked against the structure of the app spec as it is read so
+// that mistakes are reported with their line number rather than surfacing
+// later as an error from the API.
+func loadSpecFile(path string, vars map[string]map[string]string) (*godo.AppSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading spec file: %s", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("Error parsing spec file %s: %s", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("spec file %s is empty", path)
+	}
+
+	root := doc.Content[0]
+
+	// Specs exported for deploy to DigitalOcean buttons wrap the app spec
+	// in a top level spec key.
+	if root.Kind == yaml.MappingNode && len(root.Content) == 2 && root.Content[0].Value == "spec" {
+		root = root.Content[1]
+	}
+
+	l := &specLoader{path: path, vars: vars, components: componentNames(root)}
+	value, err := l.convert(root, reflect.TypeOf(godo.AppSpec{}), "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The converted value matches the app spec's JSON encoding, so decoding
+	// it can't fail on anything that wasn't already reported above.
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing spec file %s: %s", path, err)
+	}
+
+	spec := &godo.AppSpec{}
+	if err := json.Unmarshal(raw, spec); err != nil {
+		return nil, fmt.Errorf("Error parsing spec file %s: %s", path, err)
+	}
+
+	return spec, nil
+}
+
+// specLoader converts the nodes of a spec file into the JSON encoding of an
+// app spec.
+type specLoader struct {
+	path string
+	vars map[string]map[string]string
+
+	// components are the names of the spec's components. A reference to
+	// one of them is a bindable variable even if a namespace of vars has
+	// the same name.
+	components map[string]bool
+}
+
+func (l *specLoader) errorf(n *yaml.Node, format string, args ...interface{}) error {
+	return &specFileError{path: l.path, line: n.Line, msg: fmt.Sprintf(format, args...)}
+}
+
+// convert returns the value of n as t would be encoded in JSON. field is
+// the path to n in the spec and is used in error messages.
+func (l *specLoader) convert(n *yaml.Node, t reflect.Type, field string) (interface{}, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return nil, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil, l.errorf(n, "%s must be a map", describeField(field))
+		}
+
+		fields := specFields(t)
+		out := map[string]interface{}{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			f, ok := fields[key.Value]
+			if !ok {
+				return nil, l.errorf(key, "unknown field %q in %s%s",
+					key.Value, describeField(field), suggest(key.Value, fieldNames(fields)))
+			}
+
+			v, err := l.convert(val, f.Type, joinField(field, key.Value))
+			if err != nil {
+				return nil, err
+			}
+			out[key.Value] = v
+		}
+
+		return out, nil
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return nil, l.errorf(n, "%s must be a list", describeField(field))
+		}
+
+		out := []interface{}{}
+		for i, item := range n.Content {
+			v, err := l.convert(item, t.Elem(), fmt.Sprintf("%s[%d]", field, i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+
+		return out, nil
+	}
+
+	if n.Kind != yaml.ScalarNode {
+		return nil, l.errorf(n, "%s must be a single value", describeField(field))
+	}
+
+	s, err := l.interpolate(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return s, nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, l.errorf(n, "%s must be true or false, got %q", describeField(field), s)
+		}
+		return b, nil
+
+	case reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return nil, l.errorf(n, "%s must be a whole number, got %q", describeField(field), s)
+		}
+		return i, nil
+	}
+
+	return nil, l.errorf(n, "%s can't be set in a spec file", describeField(field))
+}
+
+// interpolate replaces the variable references in a scalar node's value.
+func (l *specLoader) interpolate(n *yaml.Node) (string, error) {
+	var err error
+	s := specVarPattern.ReplaceAllStringFunc(n.Value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		m := specVarPattern.FindStringSubmatch(match)
+		ns, ok := l.vars[m[1]]
+		if !ok {
+			return match
+		}
+
+		v, ok := ns[m[2]]
+		if !ok && l.components[m[1]] {
+			return match
+		}
+		if !ok && err == nil {
+			err = l.errorf(n, "undefined variable %s.%s", m[1], m[2])
+		}
+
+		return v
+	})
+
+	return s, err
+}
+
+// componentNames returns the names of the components in an app spec node.
+// Names that are themselves interpolated are left out.
+func componentNames(root *yaml.Node) map[string]bool {
+	names := map[string]bool{}
+	if root.Kind != yaml.MappingNode {
+		return names
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "services", "static_sites", "workers", "jobs", "databases":
+		default:
+			continue
+		}
+
+		for _, c := range root.Content[i+1].Content {
+			if c.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(c.Content); j += 2 {
+				if c.Content[j].Value == "name" && !strings.Contains(c.Content[j+1].Value, "${") {
+					names[c.Content[j+1].Value] = true
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// specFields returns the fields of an app spec struct by their JSON name.
+func specFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = f
+		}
+	}
+
+	return fields
+}
+
+func fieldNames(fields map[string]reflect.StructField) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func describeField(field string) string {
+	if field == "" {
+		return "the app spec"
+	}
+	return field
+}
+
+// overlayImage sets the source of the named components in spec to image,
+// removing any other source they had, and returns the names of the updated
+// components. With no names, every service, worker and job that doesn't
+// build from a Git repository is updated.
+func overlayImage(spec *godo.AppSpec, image func() *godo.ImageSourceSpec, names []string) (map[string]bool, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	use := func(name string, git *godo.GitSourceSpec, github *godo.GitHubSourceSpec) bool {
+		if len(names) > 0 {
+			return wanted[name]
+		}
+		return git == nil && github == nil
+	}
+
+	found := map[string]bool{}
+	for _, s := range spec.Services {
+		if use(s.Name, s.Git, s.GitHub) {
+			found[s.Name] = true
+			s.Image, s.Git, s.GitHub = image(), nil, nil
+			s.DockerfilePath, s.BuildCommand, s.SourceDir, s.EnvironmentSlug = "", "", "", ""
+		}
+	}
+	for _, w := range spec.Workers {
+		if use(w.Name, w.Git, w.GitHub) {
+			found[w.Name] = true
+			w.Image, w.Git, w.GitHub = image(), nil, nil
+			w.DockerfilePath, w.BuildCommand, w.SourceDir, w.EnvironmentSlug = "", "", "", ""
+		}
+	}
+	for _, j := range spec.Jobs {
+		if use(j.Name, j.Git, j.GitHub) {
+			found[j.Name] = true
+			j.Image, j.Git, j.GitHub = image(), nil, nil
+			j.DockerfilePath, j.BuildCommand, j.SourceDir, j.EnvironmentSlug = "", "", "", ""
+		}
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("spec_components: no service, worker or job named %q in the spec file", name)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("the spec file has no services, workers or jobs to deploy the image to; " +
+			"name them with spec_components")
+	}
+
+	return found, nil
+}
+
+// writeSpec writes spec to path so that it can be applied with doctl. It is
+// written as JSON if path ends in .json and as YAML otherwise.
+func writeSpec(spec *godo.AppSpec, path string) error {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding app spec: %s", err)
+	}
+
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		// Going through JSON keeps the field names and order of the app
+		// spec's JSON encoding, which is what doctl expects.
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("Error encoding app spec: %s", err)
+		}
+
+		// The JSON style is kept by the parsed nodes, so clear it to have
+		// them written in block style.
+		clearStyle(&doc)
+		if data, err = yaml.Marshal(&doc); err != nil {
+			return fmt.Errorf("Error encoding app spec: %s", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Error writing app spec: %s", err)
+	}
+
+	// The spec may include secret values, so it is only readable by the
+	// user.
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("Error writing app spec: %s", err)
+	}
+
+	return nil
+}
+
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// specHash returns the SHA-256 hash of the JSON encoding of spec, which can
+// be used to tell whether two deployments submitted the same spec.
+func specHash(spec *godo.AppSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("Error encoding app spec: %s", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
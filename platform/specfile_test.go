@@ -0,0 +1,248 @@
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func writeSpecFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "specfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadSpecFile(t *testing.T) {
+	vars := map[string]map[string]string{
+		"var":   {"size": "basic-xs"},
+		"image": {"tag": "v1"},
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		path := writeSpecFile(t, "app.yaml", `
+name: sample
+region: nyc
+services:
+- name: web
+  instance_size_slug: ${var.size}
+  instance_count: 2
+  http_port: 8080
+  envs:
+  - key: DATABASE_URL
+    value: ${db.DATABASE_URL}
+  - key: VERSION
+    value: ${image.tag}
+  - key: LITERAL
+    value: $${var.size}
+  routes:
+  - path: /
+databases:
+- name: db
+  engine: PG
+  production: false
+`)
+
+		spec, err := loadSpecFile(path, vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if spec.Name != "sample" || spec.Region != "nyc" || len(spec.Services) != 1 || len(spec.Databases) != 1 {
+			t.Fatalf("unexpected spec: %s", godo.Stringify(spec))
+		}
+
+		svc := spec.Services[0]
+		if svc.InstanceSizeSlug != "basic-xs" || svc.InstanceCount != 2 || svc.HTTPPort != 8080 {
+			t.Errorf("unexpected service: %s", godo.Stringify(svc))
+		}
+
+		want := map[string]string{
+			"DATABASE_URL": "${db.DATABASE_URL}",
+			"VERSION":      "v1",
+			"LITERAL":      "${var.size}",
+		}
+		for _, e := range svc.Envs {
+			if want[e.Key] != e.Value {
+				t.Errorf("env %s: got %q, want %q", e.Key, e.Value, want[e.Key])
+			}
+		}
+	})
+
+	t.Run("json wrapped in spec", func(t *testing.T) {
+		path := writeSpecFile(t, "app.json", `{"spec": {"name": "sample", "workers": [{"name": "worker"}]}}`)
+
+		spec, err := loadSpecFile(path, vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if spec.Name != "sample" || len(spec.Workers) != 1 || spec.Workers[0].Name != "worker" {
+			t.Errorf("unexpected spec: %s", godo.Stringify(spec))
+		}
+	})
+
+	t.Run("component named after a namespace", func(t *testing.T) {
+		path := writeSpecFile(t, "app.yaml", `
+name: sample
+services:
+- name: app
+  envs:
+  - key: URL
+    value: ${app.PUBLIC_URL}
+  - key: TAG
+    value: ${image.tag}
+`)
+
+		spec, err := loadSpecFile(path, map[string]map[string]string{
+			"app":   {"name": "web"},
+			"image": {"tag": "v1"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		envs := spec.Services[0].Envs
+		if envs[0].Value != "${app.PUBLIC_URL}" || envs[1].Value != "v1" {
+			t.Errorf("unexpected envs: %s", godo.Stringify(envs))
+		}
+	})
+
+	errCases := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{
+			name:     "unknown field",
+			contents: "name: sample\nservices:\n- name: web\n  instance_sise_slug: basic-xs\n",
+			err:      `:4: unknown field "instance_sise_slug" in services[0], did you mean "instance_size_slug"?`,
+		},
+		{
+			name:     "wrong type",
+			contents: "name: sample\nservices:\n- name: web\n  instance_count: two\n",
+			err:      `:4: services[0].instance_count must be a whole number, got "two"`,
+		},
+		{
+			name:     "not a list",
+			contents: "name: sample\nservices:\n  name: web\n",
+			err:      `:3: services must be a list`,
+		},
+		{
+			name:     "undefined variable",
+			contents: "name: sample\nregion: ${var.region}\n",
+			err:      `:2: undefined variable var.region`,
+		},
+	}
+
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeSpecFile(t, "app.yaml", tc.contents)
+
+			_, err := loadSpecFile(path, vars)
+			if err == nil || !strings.HasSuffix(err.Error(), tc.err) {
+				t.Errorf("got error %v, want one ending %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestOverlayImage(t *testing.T) {
+	image := func() *godo.ImageSourceSpec {
+		return &godo.ImageSourceSpec{Repository: "app", Tag: "v1"}
+	}
+	newSpec := func() *godo.AppSpec {
+		return &godo.AppSpec{
+			Services: []*godo.AppServiceSpec{
+				{Name: "web", DockerfilePath: "Dockerfile"},
+				{Name: "api", GitHub: &godo.GitHubSourceSpec{Repo: "foo/api"}},
+			},
+			Workers: []*godo.AppWorkerSpec{{Name: "worker"}},
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		spec := newSpec()
+		found, err := overlayImage(spec, image, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !found["web"] || !found["worker"] || found["api"] {
+			t.Errorf("unexpected components: %v", found)
+		}
+		if spec.Services[0].Image == nil || spec.Services[0].DockerfilePath != "" {
+			t.Errorf("unexpected service: %s", godo.Stringify(spec.Services[0]))
+		}
+		if spec.Services[1].Image != nil {
+			t.Errorf("unexpected image on api: %s", godo.Stringify(spec.Services[1]))
+		}
+	})
+
+	t.Run("named", func(t *testing.T) {
+		spec := newSpec()
+		found, err := overlayImage(spec, image, []string{"api"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(found) != 1 || spec.Services[1].Image == nil || spec.Services[1].GitHub != nil {
+			t.Errorf("unexpected spec: %s", godo.Stringify(spec))
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := overlayImage(newSpec(), image, []string{"nope"}); err == nil {
+			t.Error("expected error for missing component")
+		}
+	})
+}
+
+func TestWriteSpec(t *testing.T) {
+	spec := &godo.AppSpec{
+		Name:   "sample",
+		Region: "nyc",
+		Services: []*godo.AppServiceSpec{{
+			Name:          "web",
+			Image:         &godo.ImageSourceSpec{Repository: "app", Tag: "1.0"},
+			InstanceCount: 2,
+			Envs: []*godo.AppVariableDefinition{
+				{Key: "PORT", Value: "8080"},
+				{Key: "DEBUG", Value: "true"},
+			},
+		}},
+	}
+
+	for _, name := range []string{"app.yaml", "app.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(filepath.Dir(writeSpecFile(t, "unused", "")), "out", name)
+			if err := writeSpec(spec, path); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			got, err := loadSpecFile(path, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			want, _ := specHash(spec)
+			if hash, _ := specHash(got); hash != want {
+				t.Errorf("spec changed when written:\ngot:  %s\nwant: %s", godo.Stringify(got), godo.Stringify(spec))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+)
+
+// appIDFile is the file in the app's data directory that records the ID of
+// the app the last deployment went to.
+const appIDFile = "app_id"
+
+// readAppID returns the ID of the app the last deployment went to, or an
+// empty string if it isn't known.
+func readAppID(dir *datadir.App) string {
+	if dir == nil {
+		return ""
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir.DataDir(), appIDFile))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// writeAppID records the ID of the app a deployment went to so the next
+// deployment goes to the same app.
+func writeAppID(dir *datadir.App, id string) error {
+	if dir == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir.DataDir(), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir.DataDir(), appIDFile), []byte(id+"\n"), 0644)
+}
+
+// tokenFile is the file in the project's data directory that holds the
+// access token saved by waypoint auth.
+const tokenFile = "digitalocean_token"
+
+// readToken returns the access token saved by waypoint auth, or an empty
+// string if there isn't one.
+func readToken(dir *datadir.Project) string {
+	if dir == nil {
+		return ""
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir.DataDir(), tokenFile))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// writeToken saves an access token so later runs can use it without it being
+// configured. Only the current user can read it.
+func writeToken(dir *datadir.Project, token string) error {
+	if dir == nil {
+		return fmt.Errorf("no data directory to save the access token in")
+	}
+
+	if err := os.MkdirAll(dir.DataDir(), 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir.DataDir(), tokenFile)
+	if err := ioutil.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return err
+	}
+
+	// WriteFile keeps the mode of an existing file.
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	defaultPollInterval = 10 * time.Second
+	defaultWaitTimeout  = 30 * time.Minute
+
+	// maxBackoff caps the delay between polls after repeated errors.
+	maxBackoff = 5 * time.Minute
+)
+
+// errWaitTimeout is returned by poller.wait once the timeout has passed.
+var errWaitTimeout = errors.New("timed out")
+
+// waitEnabled reports whether deploy should wait for the deployment to
+// finish, which it does unless wait is set to false.
+func (p *Platform) waitEnabled() bool {
+	return p.config.Wait == nil || *p.config.Wait
+}
+
+// poller paces the requests made while waiting for App Platform. It polls
+// every interval, backs off exponentially while requests fail with
+// transient errors, and slows down when the token is close to its API rate
+// limit so that many deployments sharing a token don't exhaust it.
+type poller struct {
+	interval time.Duration
+	timeout  time.Duration
+	deadline time.Time
+	delay    time.Duration
+	failures int
+}
+
+func newPoller(interval, timeout time.Duration) *poller {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+
+	return &poller{
+		interval: interval,
+		timeout:  timeout,
+		deadline: time.Now().Add(timeout),
+		delay:    interval,
+	}
+}
+
+// wait sleeps until the next poll, or until the deadline if that comes
+// first. It returns ctx's error if ctx is done first and errWaitTimeout if
+// the timeout has passed.
+func (w *poller) wait(ctx context.Context) error {
+	left := time.Until(w.deadline)
+	if left <= 0 {
+		return errWaitTimeout
+	}
+
+	delay := w.delay
+	if delay > left {
+		delay = left
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// done records the outcome of a request and sets the delay before the next
+// poll. It reports whether the request succeeded, and returns err if it
+// failed in a way that retrying won't fix.
+func (w *poller) done(resp *godo.Response, err error) (bool, error) {
+	if err != nil {
+		if !isTransient(err) {
+			return false, err
+		}
+
+		w.failures++
+		w.delay = backoff(w.interval, w.failures)
+		return false, nil
+	}
+
+	w.failures = 0
+	w.delay = w.interval
+	if resp != nil {
+		if d := rateDelay(resp.Rate, time.Now()); d > w.delay {
+			w.delay = d
+		}
+	}
+
+	return true, nil
+}
+
+// backoff returns the delay before retrying after the given number of
+// consecutive failures.
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+// rateDelay returns the delay between requests that spreads the requests
+// remaining in the current rate limit window evenly until it resets.
+func rateDelay(rate godo.Rate, now time.Time) time.Duration {
+	if rate.Limit == 0 || rate.Reset.IsZero() {
+		return 0
+	}
+
+	until := rate.Reset.Sub(now)
+	if until <= 0 {
+		return 0
+	}
+
+	return until / time.Duration(rate.Remaining+1)
+}
+
+// isTransient reports whether a failed request is worth retrying: it was
+// rate limited, failed on the server, or never got a response.
+func isTransient(err error) bool {
+	if e, ok := err.(*godo.ErrorResponse); ok {
+		if e.Response == nil {
+			return false
+		}
+
+		code := e.Response.StatusCode
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// deploymentSince returns the app's most recent deployment if it was created
+// after since, or nil if App Platform hasn't created one since. since should
+// come from App Platform, such as the app's LastDeploymentCreatedAt before
+// it was updated, so that the comparison isn't thrown by clock skew.
+func deploymentSince(ctx context.Context, client *godo.Client, appID string, since time.Time) (*godo.Deployment, *godo.Response, error) {
+	deployments, resp, err := client.Apps.ListDeployments(ctx, appID, &godo.ListOptions{PerPage: 1})
+	if err != nil || len(deployments) == 0 {
+		return nil, resp, err
+	}
+
+	if !isNewDeployment(deployments[0], since) {
+		return nil, resp, nil
+	}
+
+	return deployments[0], resp, nil
+}
+
+// isNewDeployment reports whether d was created after since.
+func isNewDeployment(d *godo.Deployment, since time.Time) bool {
+	return d != nil && d.CreatedAt.After(since)
+}
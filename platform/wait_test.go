@@ -0,0 +1,141 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 20 * time.Second},
+		{3, 80 * time.Second},
+		{10, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(10*time.Second, tt.failures); got != tt.want {
+			t.Errorf("backoff after %d failures: got %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRateDelay(t *testing.T) {
+	now := time.Now()
+	reset := godo.Timestamp{Time: now.Add(time.Hour)}
+
+	tests := []struct {
+		name string
+		rate godo.Rate
+		want time.Duration
+	}{
+		{"no rate", godo.Rate{}, 0},
+		{"plenty remaining", godo.Rate{Limit: 5000, Remaining: 3599, Reset: reset}, time.Second},
+		{"exhausted", godo.Rate{Limit: 5000, Remaining: 0, Reset: reset}, time.Hour},
+		{"reset passed", godo.Rate{Limit: 5000, Remaining: 0, Reset: godo.Timestamp{Time: now.Add(-time.Minute)}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rateDelay(tt.rate, now); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPollerDone(t *testing.T) {
+	w := newPoller(10*time.Second, time.Minute)
+
+	unavailable := &godo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}}
+	if ok, err := w.done(nil, unavailable); ok || err != nil {
+		t.Fatalf("got %v, %v for a transient error, want a retry", ok, err)
+	}
+	if ok, err := w.done(nil, errors.New("connection reset")); ok || err != nil {
+		t.Fatalf("got %v, %v for a connection error, want a retry", ok, err)
+	}
+	if w.delay != 40*time.Second {
+		t.Errorf("got delay %s after two failures, want 40s", w.delay)
+	}
+
+	if ok, err := w.done(&godo.Response{}, nil); !ok || err != nil {
+		t.Fatalf("got %v, %v for a success", ok, err)
+	}
+	if w.delay != 10*time.Second {
+		t.Errorf("got delay %s after a success, want 10s", w.delay)
+	}
+
+	notFound := &godo.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	if _, err := w.done(nil, notFound); err != notFound {
+		t.Errorf("got %v for a not found error, want it returned", err)
+	}
+	if _, err := w.done(nil, context.Canceled); err != context.Canceled {
+		t.Errorf("got %v for a cancelled request, want it returned", err)
+	}
+}
+
+func TestPollerWaitDeadline(t *testing.T) {
+	w := newPoller(time.Second, 50*time.Millisecond)
+	w.delay = time.Hour
+
+	start := time.Now()
+	if err := w.wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("slept %s past a 50ms timeout", elapsed)
+	}
+
+	if err := w.wait(context.Background()); err != errWaitTimeout {
+		t.Errorf("got %v after the deadline, want errWaitTimeout", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	if d, err := parseDuration("poll_interval", "", time.Second); err != nil || d != time.Second {
+		t.Errorf("got %s, %v for an unset option", d, err)
+	}
+	if d, err := parseDuration("poll_interval", "5m", time.Second); err != nil || d != 5*time.Minute {
+		t.Errorf("got %s, %v for 5m", d, err)
+	}
+	for _, v := range []string{"5", "0s", "-1m"} {
+		if _, err := parseDuration("poll_interval", v, time.Second); err == nil {
+			t.Errorf("expected error for %q", v)
+		}
+	}
+}
+
+func TestIsNewDeployment(t *testing.T) {
+	since := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		deployment *godo.Deployment
+		want       bool
+	}{
+		{"none", nil, false},
+		{"previous deployment", &godo.Deployment{CreatedAt: since}, false},
+		{"older deployment", &godo.Deployment{CreatedAt: since.Add(-time.Hour)}, false},
+		{"new deployment", &godo.Deployment{CreatedAt: since.Add(time.Second)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNewDeployment(tt.deployment, since); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !isNewDeployment(&godo.Deployment{CreatedAt: since}, time.Time{}) {
+		t.Error("the first deployment of a new app isn't new")
+	}
+}
@@ -0,0 +1,183 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/datadir"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+	"github.com/hashicorp/waypoint/builtin/docker"
+)
+
+const (
+	// defaultWorkspace is the workspace Waypoint uses when none is selected.
+	defaultWorkspace = "default"
+
+	// defaultNameTemplate names the apps of workspaces other than the
+	// default one when name_template isn't set.
+	defaultNameTemplate = "{{app}}-{{workspace}}"
+)
+
+// invalidNameChars matches the characters App Platform doesn't allow in app
+// names.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// validateNameTemplate checks that name_template only uses the {{app}} and
+// {{workspace}} placeholders.
+func validateNameTemplate(tmpl string) error {
+	rest := strings.NewReplacer("{{app}}", "", "{{workspace}}", "").Replace(tmpl)
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fmt.Errorf("name_template %q may only use the {{app}} and {{workspace}} placeholders", tmpl)
+	}
+
+	return nil
+}
+
+// appName returns the name of the app to deploy to. Each workspace gets its
+// own app named by name_template. Without a template, the default workspace
+// uses the app's name, so that apps deployed before workspaces were taken
+// into account keep their name, and other workspaces use defaultNameTemplate.
+func (p *Platform) appName(src *component.Source, job *component.JobInfo) string {
+	app := src.App
+	if p.config.Name != "" {
+		app = p.config.Name
+	}
+
+	workspace := defaultWorkspace
+	if job != nil && job.Workspace != "" {
+		workspace = job.Workspace
+	}
+
+	tmpl := p.config.NameTemplate
+	if tmpl == "" {
+		if workspace == defaultWorkspace {
+			return app
+		}
+		tmpl = defaultNameTemplate
+	}
+
+	return strings.NewReplacer("{{app}}", app, "{{workspace}}", workspaceName(workspace)).Replace(tmpl)
+}
+
+// workspaceName makes a workspace name safe to use in an app name.
+func workspaceName(workspace string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(workspace), "-")
+	return strings.Trim(name, "-")
+}
+
+// managedComponents returns the names of the components and databases
+// deploying to the app named name puts in it: those of the spec file if one
+// is set, or those built from the configuration.
+func (p *Platform) managedComponents(src *component.Source, job *component.JobInfo, name string) ([]string, error) {
+	var spec *godo.AppSpec
+	if p.config.SpecFile != "" {
+		// Only the image is missing, which doesn't name anything.
+		var err error
+		spec, err = loadSpecFile(appPath(src, p.config.SpecFile), specVars(src, job, &docker.Image{}, p.config.SpecVars))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		spec = p.configSpec(name, func() *godo.ImageSourceSpec { return nil }, nil)
+	}
+
+	managed := append(specComponents(spec), specDatabases(spec)...)
+	managed = append(managed, specStaticSites(spec)...)
+
+	return managed, nil
+}
+
+// unmanagedComponents returns the components and databases in spec that
+// aren't named in managed.
+func unmanagedComponents(spec *godo.AppSpec, managed []string) []string {
+	if spec == nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, name := range managed {
+		known[name] = true
+	}
+
+	names := append(specComponents(spec), specDatabases(spec)...)
+	names = append(names, specStaticSites(spec)...)
+
+	var unmanaged []string
+	for _, name := range names {
+		if !known[name] {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+
+	return unmanaged
+}
+
+// DestroyWorkspaceFunc implements component.WorkspaceDestroyer
+func (p *Platform) DestroyWorkspaceFunc() interface{} {
+	return p.destroyWorkspace
+}
+
+// destroyWorkspace deletes the workspace's app. It is called once every
+// deployment in the workspace has been destroyed, to remove an app that
+// destroying the deployments left in place, and does nothing if the app is
+// already gone. Like destroy, it leaves the app in place if it holds
+// components that Waypoint doesn't manage, such as those kept by merge. An
+// app set by app_id is never deleted, as every workspace deploys to it.
+func (p *Platform) destroyWorkspace(
+	ctx context.Context,
+	ui terminal.UI,
+	src *component.Source,
+	job *component.JobInfo,
+	dir *datadir.Project,
+) error {
+	p.useStoredToken(dir)
+
+	u := ui.Status()
+	defer u.Close()
+
+	if p.config.AppID != "" {
+		u.Step(terminal.StatusWarn, fmt.Sprintf("App %s is set by app_id and may be shared by other workspaces, leaving it in place. "+
+			"Run `doctl apps delete %s` to delete it", p.config.AppID, p.config.AppID))
+		return nil
+	}
+
+	name := p.appName(src, job)
+	u.Update(fmt.Sprintf("Destroying App Platform app for workspace: %s", name))
+
+	app, err := p.findExistingApp(ctx, name, "", u)
+	if err != nil {
+		return err
+	}
+	if app == nil {
+		u.Step(terminal.StatusOK, fmt.Sprintf("No App Platform app named %s, nothing to destroy", name))
+		return nil
+	}
+
+	managed, err := p.managedComponents(src, job, name)
+	if err != nil {
+		return err
+	}
+
+	if unmanaged := unmanagedComponents(app.Spec, managed); len(unmanaged) > 0 {
+		u.Step(terminal.StatusWarn, fmt.Sprintf("App %s (%s) holds components not managed by Waypoint: %s, leaving it in place",
+			name, app.ID, strings.Join(unmanaged, ", ")))
+		return nil
+	}
+
+	_, err = p.client.Apps.Delete(ctx, app.ID)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("Error deleting app (%s): %s", app.ID, err)
+	}
+
+	u.Update(fmt.Sprintf("Waiting for app %s (%s) to be deleted", name, app.ID))
+	if err := p.waitForAppDeletion(ctx, app.ID); err != nil {
+		return err
+	}
+
+	u.Step(terminal.StatusOK, fmt.Sprintf("Deleted App Platform app %s (%s)", name, app.ID))
+	return nil
+}
@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/waypoint-plugin-sdk/component"
+	"github.com/hashicorp/waypoint-plugin-sdk/terminal"
+)
+
+func TestAppName(t *testing.T) {
+	src := &component.Source{App: "web"}
+
+	tests := []struct {
+		name      string
+		config    DeployConfig
+		workspace string
+		want      string
+	}{
+		{"default workspace", DeployConfig{}, "default", "web"},
+		{"no job info", DeployConfig{}, "", "web"},
+		{"other workspace", DeployConfig{}, "staging", "web-staging"},
+		{"configured name", DeployConfig{Name: "site"}, "staging", "site-staging"},
+		{"template", DeployConfig{NameTemplate: "{{workspace}}-{{app}}"}, "default", "default-web"},
+		{"unsafe workspace", DeployConfig{}, "Feature_Branch/1", "web-feature-branch-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Platform{config: tt.config}
+
+			var job *component.JobInfo
+			if tt.workspace != "" {
+				job = &component.JobInfo{Workspace: tt.workspace}
+			}
+
+			if got := p.appName(src, job); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNameTemplate(t *testing.T) {
+	for _, tmpl := range []string{"", "{{app}}", "{{app}}-{{workspace}}", "prefix-{{workspace}}"} {
+		if err := validateNameTemplate(tmpl); err != nil {
+			t.Errorf("unexpected error for %q: %s", tmpl, err)
+		}
+	}
+
+	for _, tmpl := range []string{"{{name}}-{{workspace}}", "{{ app }}", "{{app}"} {
+		if err := validateNameTemplate(tmpl); err == nil {
+			t.Errorf("expected error for %q", tmpl)
+		}
+	}
+}
+
+func TestDestroyWorkspaceUnmanaged(t *testing.T) {
+	src := &component.Source{App: "web"}
+	job := &component.JobInfo{Workspace: "staging"}
+
+	live := &godo.AppSpec{
+		Name:      "web-staging",
+		Services:  []*godo.AppServiceSpec{{Name: "web-staging"}},
+		Workers:   []*godo.AppWorkerSpec{{Name: "queue"}},
+		Databases: []*godo.AppDatabaseSpec{{Name: "db"}},
+	}
+
+	specFile := writeSpecFile(t, "app.yaml", `
+name: sample
+services:
+- name: web-staging
+workers:
+- name: queue
+databases:
+- name: db
+`)
+
+	tests := []struct {
+		name   string
+		config DeployConfig
+		want   []string
+	}{
+		{"default service", DeployConfig{}, []string{"queue", "db"}},
+		{"merged worker kept", DeployConfig{Merge: true, Databases: []*DatabaseConfig{{Name: "db"}}}, []string{"queue"}},
+		{"all managed", DeployConfig{
+			Workers:   []*WorkerConfig{{Name: "queue"}},
+			Databases: []*DatabaseConfig{{Name: "db"}},
+		}, nil},
+		{"spec file", DeployConfig{SpecFile: specFile}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Platform{config: tt.config}
+
+			managed, err := p.managedComponents(src, job, p.appName(src, job))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got := unmanagedComponents(live, managed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got unmanaged %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := unmanagedComponents(nil, nil); got != nil {
+		t.Errorf("got %v for an app without a spec, want none", got)
+	}
+}
+
+func TestDestroyWorkspaceAppID(t *testing.T) {
+	ctx := context.Background()
+
+	// Without a client, any request to delete the app would panic.
+	p := &Platform{config: DeployConfig{AppID: "app-id", AccessToken: "token"}}
+	src := &component.Source{App: "web"}
+	job := &component.JobInfo{Workspace: "staging"}
+
+	if err := p.destroyWorkspace(ctx, terminal.ConsoleUI(ctx), src, job, nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}